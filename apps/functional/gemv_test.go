@@ -113,6 +113,7 @@ func TestNetworkWithBigStep(t *testing.T) {
 			t.Logf("Dot Product Finished")
 		},
 	}
+	dotProduct.State = new(MatVecState)
 	dotProduct.AddInputChannel(vecToDot)
 	dotProduct.AddInputChannel(matToDot)
 	dotProduct.AddOutputChannel(dotOutput)
@@ -147,3 +148,88 @@ func TestNetworkWithBigStep(t *testing.T) {
 	t.Logf("Dot Product finished at %v", dotProduct.TickLowerBound())
 	t.Logf("Dot product delay is %d", dotTime)
 }
+
+// BenchmarkGEMV times simulating a smaller version of the network above,
+// without the logging, so that the time goes into the simulator itself.
+func BenchmarkGEMV(b *testing.B) {
+	M := 256
+	N := 16
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+	for iter := 0; iter < b.N; iter++ {
+		vecToDot := core.MakeCommunicationChannel[datatypes.
+			Vector[datatypes.FixedPoint]](1)
+		matToDot := core.MakeCommunicationChannel[datatypes.
+			Vector[datatypes.FixedPoint]](8)
+		dotOutput := core.MakeCommunicationChannel[datatypes.FixedPoint](M)
+		ctx := core.MakePrimitiveContext(nil)
+
+		makeVector := func(offset int) datatypes.Vector[datatypes.FixedPoint] {
+			result := datatypes.NewVector[datatypes.FixedPoint](N)
+			for i := 0; i < N; i++ {
+				val := datatypes.FixedPoint{Tp: fpt}
+				val.SetInt(big.NewInt(int64(i + offset)))
+				result.Set(i, val)
+			}
+			return result
+		}
+		vecProducer := core.SimpleNode[any]{
+			RunFunc: func(node *core.SimpleNode[any]) {
+				node.OutputChannel(0).
+					Enqueue(core.MakeChannelElement(node.TickLowerBound(), makeVector(0)))
+			},
+		}
+		vecProducer.AddOutputChannel(vecToDot)
+		ctx.AddChild(&vecProducer)
+
+		matProducer := core.SimpleNode[any]{
+			RunFunc: func(node *core.SimpleNode[any]) {
+				for j := 0; j < M; j++ {
+					core.AdvanceUntilCanEnqueue(node, 0)
+					node.OutputChannel(0).Enqueue(core.
+						MakeChannelElement(node.TickLowerBound(), makeVector(j)))
+					node.IncrCycles(core.NewTime(32))
+				}
+			},
+		}
+		matProducer.AddOutputChannel(matToDot)
+		ctx.AddChild(&matProducer)
+
+		dotProduct := core.SimpleNode[any]{
+			RunFunc: func(node *core.SimpleNode[any]) {
+				vec := core.DequeueInputChansByID(node, 0)[0].Data.(datatypes.
+					Vector[datatypes.FixedPoint])
+				for j := 0; j < M; j++ {
+					matVec := core.DequeueInputChansByID(node, 1)[0].Data.(datatypes.
+						Vector[datatypes.FixedPoint])
+					sum := datatypes.FixedPoint{Tp: fpt}
+					for i := 0; i < N; i++ {
+						mul := datatypes.FixedMulFull(matVec.Get(i), vec.Get(i)).FixedToFixed(fpt)
+						sum = datatypes.FixedAdd(sum, mul)
+					}
+					core.AdvanceUntilCanEnqueue(node, 0)
+					node.OutputChannel(0).Enqueue(core.
+						MakeChannelElement(node.TickLowerBound(), sum))
+					node.IncrCycles(core.OneTick)
+				}
+			},
+		}
+		dotProduct.AddInputChannel(vecToDot)
+		dotProduct.AddInputChannel(matToDot)
+		dotProduct.AddOutputChannel(dotOutput)
+		ctx.AddChild(&dotProduct)
+
+		checker := core.SimpleNode[any]{
+			RunFunc: func(node *core.SimpleNode[any]) {
+				for i := 0; i < M; i++ {
+					core.DequeueInputChansByID(node, 0)
+				}
+			},
+		}
+		checker.AddInputChannel(dotOutput)
+		ctx.AddChild(&checker)
+
+		if _, err := core.Simulate(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
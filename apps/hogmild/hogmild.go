@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"math/big"
+	"os"
 
 	"github.com/stanford-ppl/DAM/core"
 	"github.com/stanford-ppl/DAM/datatypes"
@@ -76,17 +77,19 @@ func addWorker(
 	paramsServerNode.AddInputChannel(updateChan)
 }
 
-func hogmild(conf *config) ([]*sample, core.Time) {
+func hogmild(conf *config) ([]*sample, core.Time, error) {
 	ctx := core.MakePrimitiveContext(nil)
 	paramsServerNode := makeParamsServer(ctx, conf)
 	for i := 0; i < int(conf.nWorkers); i++ {
 		addWorker(ctx, conf, paramsServerNode)
 	}
 
-	ctx.Init()
-	ctx.Run()
+	result, err := core.Simulate(ctx, core.WithOutputLog(core.MakeOutputLog(os.Stdout)))
+	if err != nil {
+		return nil, core.Time{}, err
+	}
 
-	return paramsServerNode.State.updateLog, *paramsServerNode.TickLowerBound()
+	return paramsServerNode.State.updateLog, *result.FinishTime(paramsServerNode), nil
 }
 
 func parseConfig() *config {
@@ -129,6 +132,10 @@ func logResult(updateLogs []*sample, finalTick core.Time) {
 
 func main() {
 	conf := parseConfig()
-	updateLogs, finalTick := hogmild(conf)
+	updateLogs, finalTick, err := hogmild(conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	logResult(updateLogs, finalTick)
 }
@@ -23,7 +23,10 @@ func TestHogmildSequential(t *testing.T) {
 		nWeightBanks:    1,
 	}
 
-	updateLogs, _ := hogmild(&conf)
+	updateLogs, _, err := hogmild(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	assert_uint_eq(t, conf.nSamples, uint(len(updateLogs)))
 
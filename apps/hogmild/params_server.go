@@ -1,10 +1,6 @@
 package main
 
-import (
-	"fmt"
-
-	"github.com/stanford-ppl/DAM/core"
-)
+import "github.com/stanford-ppl/DAM/core"
 
 type weightVersionUpdate struct {
 	time    core.Time
@@ -200,15 +196,15 @@ func makeBundles(max int) [][]int {
 func receiveAllSamples(node *core.SimpleNode[paramsServerState]) {
 	channelBundles := makeBundles(int(node.State.conf.nWorkers))
 	for len(node.State.updateLog) < int(node.State.conf.nSamples) {
-		fmt.Printf("Param servers got %d updates\n", len(node.State.updateLog))
+		core.Printf(node, "Param servers got %d updates\n", len(node.State.updateLog))
 
 		if !foldReady(node) {
 			node.AdvanceToTime(&node.State.foldReadyAt)
 		}
 
-		fmt.Println("Param server waiting for updates")
+		core.Println(node, "Param server waiting for updates")
 		err, ces := core.DequeueInputBundles(node, channelBundles...)
-		fmt.Printf("Param server got %d updates\n", len(ces))
+		core.Printf(node, "Param server got %d updates\n", len(ces))
 		if err < 0 {
 			panic("Did not receive all of the gradients")
 		}
@@ -241,9 +237,9 @@ func runParamsServer(node *core.SimpleNode[paramsServerState]) {
 		node.IncrCycles(core.NewTime(1))
 	}
 
-	fmt.Printf("params server sent %d samples\n", node.State.nextSample)
+	core.Printf(node, "params server sent %d samples\n", node.State.nextSample)
 	receiveAllSamples(node)
 
-	print("params server shutting down\n")
+	core.Println(node, "params server shutting down")
 	shutdown(node)
 }
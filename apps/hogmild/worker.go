@@ -1,10 +1,6 @@
 package main
 
-import (
-	"fmt"
-
-	"github.com/stanford-ppl/DAM/core"
-)
+import "github.com/stanford-ppl/DAM/core"
 
 type workerState struct {
 	conf     *config
@@ -34,7 +30,7 @@ func computeGradient(
 		core.NewTime(int64(totalLatency)))
 	node.OutputChannel(0).Enqueue(ce)
 	node.State.sent += 1
-	fmt.Printf("Worker_%d sent %d samples\n", node.ID(), node.State.sent)
+	core.Printf(node, "Worker_%d sent %d samples\n", node.ID(), node.State.sent)
 
 	node.IncrCycles(core.NewTime(int64(node.State.conf.gradientII)))
 	return false
@@ -44,7 +40,7 @@ func runWorker(node *core.SimpleNode[workerState]) {
 	for {
 		ces_with_statuses := core.DequeueInputChansByID(node, 0)
 		node.State.received += uint(len(ces_with_statuses))
-		fmt.Printf("Worker_%d got %d samples\n", node.ID(), node.State.received)
+		core.Printf(node, "Worker_%d got %d samples\n", node.ID(), node.State.received)
 		for _, ce_with_status := range ces_with_statuses {
 			switch ce_with_status.Status {
 			case core.Ok:
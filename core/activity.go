@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+)
+
+// An Activity is what a node was doing while its time advanced.
+type Activity uint8
+
+const (
+	Compute Activity = iota
+	// Waiting for room on a full output
+	Stalled
+	// Waiting for data on an empty input
+	Starved
+	// Explicitly doing nothing
+	Idle
+
+	numActivities
+)
+
+func (activity Activity) String() string {
+	switch activity {
+	case Compute:
+		return "Compute"
+	case Stalled:
+		return "Stalled"
+	case Starved:
+		return "Starved"
+	case Idle:
+		return "Idle"
+	}
+	return "X"
+}
+
+// An ActivityTracker attributes the time it advances to its current Activity.
+type ActivityTracker interface {
+	// SetActivity sets what the time advanced from now on is attributed to, and returns the previous activity.
+	SetActivity(Activity) Activity
+	ActivityCycles(Activity) *Time
+}
+
+var _ ActivityTracker = (*TickTime)(nil)
+
+func (prim *TickTime) SetActivity(activity Activity) (previous Activity) {
+	prim.tickMutex.Lock()
+	defer prim.tickMutex.Unlock()
+	previous = prim.activity
+	prim.activity = activity
+	return
+}
+
+// ActivityCycles returns the number of cycles of the node's clock spent on activity.
+func (prim *TickTime) ActivityCycles(activity Activity) *Time {
+	prim.tickMutex.RLock()
+	defer prim.tickMutex.RUnlock()
+	return prim.domain.TimeToCycles(&prim.activityCycles[activity])
+}
+
+// attribute charges the time between from and the current time to the current activity.
+// The caller must hold the tickMutex.
+func (prim *TickTime) attribute(from *Time) {
+	if prim.tickCount.IsInf() {
+		// Finishing isn't an activity.
+		return
+	}
+	elapsed := new(Time).Sub(&prim.tickCount, from)
+	prim.activityCycles[prim.activity].Add(&prim.activityCycles[prim.activity], elapsed)
+}
+
+// during attributes the time that node advances to activity, until the returned function is called.
+// It does nothing if node doesn't track its activity.
+func during(node any, activity Activity) (restore func()) {
+	tracker, ok := node.(ActivityTracker)
+	if !ok {
+		return func() {}
+	}
+	previous := tracker.SetActivity(activity)
+	return func() {
+		tracker.SetActivity(previous)
+	}
+}
+
+// NodeUtilization breaks down the time a node spent on each activity, in cycles of its clock.
+type NodeUtilization struct {
+	Context string
+	Cycles  [numActivities]*Time
+}
+
+func (utilization *NodeUtilization) Total() *Time {
+	total := NewTime(0)
+	for _, cycles := range utilization.Cycles {
+		total.Add(total, cycles)
+	}
+	return total
+}
+
+// Fraction returns the fraction of the node's time that was spent on activity.
+func (utilization *NodeUtilization) Fraction(activity Activity) float64 {
+	total := utilization.Total().GetTime()
+	if total.Sign() == 0 {
+		return 0
+	}
+	cycles := utilization.Cycles[activity].GetTime()
+	fraction, _ := new(big.Rat).SetFrac(&cycles, &total).Float64()
+	return fraction
+}
+
+// CollectUtilization returns the utilization of every context under root which tracks its activity.
+func CollectUtilization(root ParentContext) (result []NodeUtilization) {
+	walkContexts(root, func(ctx Context) {
+		tracker, ok := ctx.(ActivityTracker)
+		if !ok {
+			return
+		}
+		utilization := NodeUtilization{Context: CtxToString(ctx)}
+		for activity := Activity(0); activity < numActivities; activity++ {
+			utilization.Cycles[activity] = tracker.ActivityCycles(activity)
+		}
+		result = append(result, utilization)
+	})
+	return
+}
+
+// WriteUtilizationTable writes a table of the cycles and the fraction of time each node spent on each activity.
+func WriteUtilizationTable(w io.Writer, utilizations []NodeUtilization) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(table, "Node\tTotal\t")
+	for activity := Activity(0); activity < numActivities; activity++ {
+		fmt.Fprintf(table, "%s\t", activity)
+	}
+	fmt.Fprintln(table)
+	for _, utilization := range utilizations {
+		fmt.Fprintf(table, "%s\t%v\t", utilization.Context, utilization.Total())
+		for activity := Activity(0); activity < numActivities; activity++ {
+			fmt.Fprintf(table, "%v (%.1f%%)\t", utilization.Cycles[activity], 100*utilization.Fraction(activity))
+		}
+		fmt.Fprintln(table)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestActivityAccounting(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Bit](4)
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			AdvanceUntilCanEnqueue(node, 0)
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			node.IncrCycles(NewTime(3))
+		}
+	}, (*any)(nil))
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		// Elements arrive at 0, 3, 6, and 9, so the consumer waits 2 cycles for each after the first.
+		for i := 0; i < 4; i++ {
+			DequeueInputChansByID(node, 0)
+			node.IncrCycles(OneTick)
+		}
+		previous := node.SetActivity(Idle)
+		node.IncrCycles(NewTime(5))
+		node.SetActivity(previous)
+	}, (*any)(nil))
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	result, err := Simulate(ctx, WithBackend(DeterministicBackend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][numActivities]int64{
+		CtxToString(producer): {Compute: 12},
+		CtxToString(consumer): {Compute: 4, Starved: 6, Idle: 5},
+	}
+	if len(result.Utilization) != len(expected) {
+		t.Fatalf("Expected %d nodes, got %d", len(expected), len(result.Utilization))
+	}
+	for _, utilization := range result.Utilization {
+		for activity, cycles := range expected[utilization.Context] {
+			if utilization.Cycles[activity].Cmp(NewTime(cycles)) != 0 {
+				t.Errorf("%s: expected %d %s cycles, got %v", utilization.Context, cycles, Activity(activity), utilization.Cycles[activity])
+			}
+		}
+	}
+
+	var table bytes.Buffer
+	if err := WriteUtilizationTable(&table, result.Utilization); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + table.String())
+	if !strings.Contains(table.String(), "6 (40.0%)") {
+		t.Errorf("Expected the consumer to be starved 40%% of the time")
+	}
+}
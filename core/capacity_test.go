@@ -0,0 +1,107 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Takes an element every ii cycles until the channel is closed, and records when each was received.
+func slowConsumer(ii int64, received *[]int64) func(*SimpleNode[any]) {
+	return func(node *SimpleNode[any]) {
+		for {
+			input := DequeueInputChansByID(node, 0)[0]
+			if input.Status == Closed {
+				return
+			}
+			time := input.Time.GetTime()
+			*received = append(*received, time.Int64())
+			node.IncrCycles(NewTime(ii))
+		}
+	}
+}
+
+func TestUnboundedChannel(t *testing.T) {
+	for _, backend := range backends {
+		var received []int64
+		var full bool
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			for i := 0; i < 100; i++ {
+				full = full || node.OutputChannel(0).IsFull()
+				if ok, _ := node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{})); !ok {
+					t.Errorf("%v: element %d wasn't sent", backend, i)
+				}
+			}
+		}, (*any)(nil))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.AdvanceToTime(NewTime(5))
+			slowConsumer(10, &received)(node)
+		}, (*any)(nil))
+		runPair(t, backend, MakeCommunicationChannel[datatypes.Bit](Unbounded), producer, consumer)
+		if full {
+			t.Errorf("%v: an unbounded channel should never be full", backend)
+		}
+		if len(received) != 100 || received[0] != 5 || received[99] != 995 {
+			t.Errorf("%v: expected 100 elements, one every 10 cycles, got %v", backend, received)
+		}
+	}
+}
+
+func TestRendezvousChannel(t *testing.T) {
+	for _, backend := range backends {
+		var received, handoffs []int64
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			for i := 0; i < 3; i++ {
+				AdvanceUntilCanEnqueue(node, 0)
+				_, handoff := node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+				time := handoff.GetTime()
+				handoffs = append(handoffs, time.Int64())
+				node.AdvanceToTime(handoff)
+				node.IncrCycles(OneTick)
+			}
+		}, (*any)(nil))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.AdvanceToTime(NewTime(4))
+			slowConsumer(5, &received)(node)
+		}, (*any)(nil))
+		runPair(t, backend, MakeCommunicationChannel[datatypes.Bit](Rendezvous), producer, consumer)
+		// The producer is ready every cycle, but has to wait for the consumer each time.
+		expected := []int64{4, 9, 14}
+		for i := range expected {
+			if len(received) != len(expected) || received[i] != expected[i] || handoffs[i] != expected[i] {
+				t.Fatalf("%v: expected handoffs at %v, got %v, received at %v", backend, expected, handoffs, received)
+			}
+		}
+		if finish := producer.TickLowerBound(); !finish.IsInf() {
+			t.Errorf("%v: expected the producer to finish, got %v", backend, finish)
+		}
+	}
+}
+
+func TestRendezvousWaitsForDestination(t *testing.T) {
+	for _, backend := range backends {
+		// Neither side can get to the other's rendezvous.
+		ctx := MakePrimitiveContext(nil)
+		first := MakeCommunicationChannel[datatypes.Bit](Rendezvous)
+		second := MakeCommunicationChannel[datatypes.Bit](Rendezvous)
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			node.OutputChannel(1).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+		}, (*any)(nil))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			DequeueInputChansByID(node, 1, 0)
+		}, (*any)(nil))
+		producer.AddOutputChannel(first)
+		producer.AddOutputChannel(second)
+		consumer.AddInputChannel(first)
+		consumer.AddInputChannel(second)
+		ctx.AddChild(producer)
+		ctx.AddChild(consumer)
+		_, err := Simulate(ctx, WithBackend(backend))
+		var deadlock *DeadlockError
+		if !errors.As(err, &deadlock) {
+			t.Errorf("%v: expected a deadlock, got %v", backend, err)
+		}
+	}
+}
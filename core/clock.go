@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// The base unit of Time is the femtosecond, so that clocks of different frequencies can share it.
+// Contexts without a clock domain count cycles in the base unit directly.
+const (
+	Femtosecond int64 = 1
+	Picosecond        = 1000 * Femtosecond
+	Nanosecond        = 1000 * Picosecond
+)
+
+// A ClockDomain is a named clock that nodes can be assigned to.
+// A nil *ClockDomain is a clock whose period is a single base unit.
+type ClockDomain struct {
+	Name string
+	// The length of a cycle, in femtoseconds
+	Period int64
+}
+
+// MakeClockDomain makes a clock domain running at frequencyHz, with its period rounded to the nearest femtosecond.
+func MakeClockDomain(name string, frequencyHz float64) *ClockDomain {
+	period := int64(math.Round(1e15 / frequencyHz))
+	if period <= 0 {
+		panic(fmt.Sprintf("Clock domain %s is too fast to represent: %v Hz", name, frequencyHz))
+	}
+	return &ClockDomain{Name: name, Period: period}
+}
+
+func (domain *ClockDomain) period() int64 {
+	if domain == nil {
+		return 1
+	}
+	return domain.Period
+}
+
+func (domain *ClockDomain) String() string {
+	if domain == nil {
+		return "base"
+	}
+	return fmt.Sprintf("%s(%.6g MHz)", domain.Name, domain.Frequency()/1e6)
+}
+
+// Frequency returns the frequency of the clock in Hz.
+func (domain *ClockDomain) Frequency() float64 {
+	return 1e15 / float64(domain.period())
+}
+
+// CyclesToTime converts a number of cycles of this clock to a Time.
+func (domain *ClockDomain) CyclesToTime(cycles *Time) *Time {
+	return new(Time).mulInt64(cycles, domain.period())
+}
+
+// TimeToCycles returns the number of whole cycles of this clock that fit in t.
+func (domain *ClockDomain) TimeToCycles(t *Time) *Time {
+	return new(Time).divInt64(t, domain.period(), false)
+}
+
+// NextEdge returns the first clock edge at or after t.
+func (domain *ClockDomain) NextEdge(t *Time) *Time {
+	cycles := new(Time).divInt64(t, domain.period(), true)
+	return cycles.mulInt64(cycles, domain.period())
+}
+
+// TimeToNanoseconds converts t to nanoseconds of wall-clock time.
+func TimeToNanoseconds(t *Time) float64 {
+	if t.IsInf() {
+		return math.Inf(1)
+	}
+	ns, _ := new(big.Rat).SetFrac(t.bigInt(), big.NewInt(Nanosecond)).Float64()
+	return ns
+}
+
+// A HasClockDomain context counts its cycles in the given clock domain.
+type HasClockDomain interface {
+	ClockDomain() *ClockDomain
+}
+
+// clockDomainOf returns the clock domain of view, or nil if it doesn't have one.
+func clockDomainOf(view ContextView) *ClockDomain {
+	if hasDomain, ok := view.(HasClockDomain); ok {
+		return hasDomain.ClockDomain()
+	}
+	return nil
+}
+
+// SetClockDomain assigns the node to domain, so that IncrCycles counts cycles of its clock
+// and AdvanceToTime waits for its next clock edge. It should be set before the simulation starts.
+func (prim *TickTime) SetClockDomain(domain *ClockDomain) {
+	prim.domain = domain
+}
+
+func (prim *TickTime) ClockDomain() *ClockDomain {
+	return prim.domain
+}
+
+// CyclesToTime converts a number of the node's cycles to a Time, such as for stamping outputs a few cycles ahead.
+func (prim *TickTime) CyclesToTime(cycles *Time) *Time {
+	return prim.domain.CyclesToTime(cycles)
+}
+
+// SetSynchronizer delays elements which cross between clock domains by the given number of cycles of the destination's clock,
+// on top of waiting for its next clock edge.
+func (cchan *CommunicationChannel) SetSynchronizer(cycles int64) *CommunicationChannel {
+	cchan.synchronizer = cycles
+	return cchan
+}
+
+// arrivalTime returns when an element sent at sent is visible to the destination.
+func (cchan *CommunicationChannel) arrivalTime(sent *Time) *Time {
+	srcDomain, dstDomain := clockDomainOf(cchan.srcCtx), clockDomainOf(cchan.dstCtx)
+	if srcDomain == dstDomain || sent.IsInf() {
+		return sent
+	}
+	arrival := dstDomain.NextEdge(sent)
+	return arrival.Add(arrival, dstDomain.CyclesToTime(NewTime(cchan.synchronizer)))
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestClockDomainConversions(t *testing.T) {
+	dram := MakeClockDomain("dram", 800e6)
+	if dram.Period != 1250*Picosecond {
+		t.Errorf("Expected an 800 MHz clock to have a period of 1250ps, got %dfs", dram.Period)
+	}
+	if dram.CyclesToTime(NewTime(4)).Cmp(NewTime(5*Nanosecond)) != 0 {
+		t.Errorf("Expected 4 cycles to take 5ns")
+	}
+	if dram.TimeToCycles(NewTime(5*Nanosecond-1)).Cmp(NewTime(3)) != 0 {
+		t.Errorf("Expected just under 5ns to be 3 whole cycles")
+	}
+	if dram.NextEdge(NewTime(1)).Cmp(NewTime(dram.Period)) != 0 || dram.NextEdge(NewTime(dram.Period)).Cmp(NewTime(dram.Period)) != 0 {
+		t.Errorf("Expected times to round up to the next edge")
+	}
+	var base *ClockDomain
+	if base.CyclesToTime(NewTime(7)).Cmp(NewTime(7)) != 0 {
+		t.Errorf("Expected contexts without a clock to count in the base unit")
+	}
+}
+
+func TestClockDomainCrossing(t *testing.T) {
+	fabric := MakeClockDomain("fabric", 1e9)
+	dram := MakeClockDomain("dram", 800e6)
+	ctx := MakePrimitiveContext(nil)
+	// Crossing into the DRAM's clock takes 2 of its cycles.
+	channel := MakeCommunicationChannel[datatypes.Bit](4).SetSynchronizer(2)
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	producer.SetClockDomain(fabric)
+	var received []*Time
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			elem := DequeueInputChansByID(node, 0)[0]
+			received = append(received, new(Time).Set(&elem.Time))
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	consumer.SetClockDomain(dram)
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	result, err := Simulate(ctx, WithBackend(DeterministicBackend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sent at 0, 1, 2, and 3ns, which is seen at the next DRAM edge plus 2 DRAM cycles.
+	expected := []int64{2500 * Picosecond, 3750 * Picosecond, 5000 * Picosecond, 6250 * Picosecond}
+	for i, arrival := range expected {
+		if received[i].Cmp(NewTime(arrival)) != 0 {
+			t.Errorf("Expected element %d at %dfs, got %v", i, arrival, received[i])
+		}
+	}
+	if result.FinishCycles(producer).Cmp(NewTime(4)) != 0 {
+		t.Errorf("Expected the producer to take 4 cycles, got %v", result.FinishCycles(producer))
+	}
+	if result.FinishCycles(consumer).Cmp(NewTime(6)) != 0 {
+		t.Errorf("Expected the consumer to take 6 cycles, got %v", result.FinishCycles(consumer))
+	}
+
+	var table bytes.Buffer
+	if err := result.WriteFinishTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + table.String())
+	if !strings.Contains(table.String(), "7.500") {
+		t.Errorf("Expected the simulation to end at 7.5ns")
+	}
+}
@@ -35,6 +35,31 @@ type ParentContext interface {
 	AddChild(Context)
 }
 
+// hasChildren is implemented by parent contexts which can list their children, so that they can be walked.
+type hasChildren interface {
+	Children() []Context
+}
+
+// walkChannels calls fn on every channel connected to ctx or anything under it, once for each end.
+func walkChannels(ctx Context, fn func(*CommunicationChannel)) {
+	walkContexts(ctx, func(child Context) {
+		if hasIO, ok := child.(hasLowLevelIO); ok {
+			utils.Foreach(hasIO.lowLevelIO().inputChannels, fn)
+			utils.Foreach(hasIO.lowLevelIO().outputChannels, fn)
+		}
+	})
+}
+
+// walkContexts calls fn on ctx and everything under it, parents before their children.
+func walkContexts(ctx Context, fn func(Context)) {
+	fn(ctx)
+	if parent, ok := ctx.(hasChildren); ok {
+		for _, child := range parent.Children() {
+			walkContexts(child, fn)
+		}
+	}
+}
+
 // This is intended to be a basic utilities mixin to parent contexts
 type ChildIDManager struct {
 	nextID int
@@ -78,6 +103,8 @@ type basicContext struct {
 	children []Context
 	parent   ParentContext
 	ID       int
+
+	sim *simulation
 }
 
 var (
@@ -102,6 +129,10 @@ func (prim *basicContext) AddChild(child Context) {
 	child.SetParent(prim)
 }
 
+func (prim *basicContext) Children() []Context {
+	return prim.children
+}
+
 func (prim *basicContext) BlockUntil(time *Time) <-chan *Time {
 	signalChan := make(chan *Time, 1)
 	go (func() {
@@ -119,9 +150,10 @@ func (prim *basicContext) Init() {
 	var wg sync.WaitGroup
 	wg.Add(len(prim.children))
 	for _, ctx := range prim.children {
+		bindSimulation(ctx, prim.sim)
 		go (func(c Context) {
-			c.Init()
-			wg.Done()
+			defer wg.Done()
+			prim.sim.guard(c, c.Init)
 		})(ctx)
 	}
 	wg.Wait()
@@ -132,16 +164,26 @@ func (prim *basicContext) ParentContext() ParentContext {
 }
 
 func (prim *basicContext) Run() {
-	var wg sync.WaitGroup
-	wg.Add(len(prim.children))
-	for _, ctx := range prim.children {
-		go (func(c Context) {
-			c.Run()
-			c.Cleanup()
-			wg.Done()
-		})(ctx)
+	if prim.parent != nil || prim.sim != nil {
+		RunChildren(prim, prim.children...)
+		return
 	}
-	wg.Wait()
+	// Run was called directly on the root rather than through Simulate, so failures are re-raised here.
+	prim.sim = newSimulation(simulationConfig{})
+	RunChildren(prim, prim.children...)
+	err := prim.sim.err()
+	prim.sim = nil
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (prim *basicContext) bindSimulation(sim *simulation) {
+	prim.sim = sim
+}
+
+func (prim *basicContext) simulation() *simulation {
+	return prim.sim
 }
 
 func (prim *basicContext) GetID() int {
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A waitRecord describes what a context goroutine is blocked on.
+type waitRecord struct {
+	ctx ContextView // The waiting context
+
+	// The context whose time is being waited on, and the time it needs to reach.
+	// target is nil if the wait isn't on another context's time.
+	target ContextView
+	until  *Time
+
+	// The channel that the wait is on behalf of, if any.
+	channel *CommunicationChannel
+
+	// Reports whether the wait can complete. Defaults to target having reached until.
+	ready func() bool
+
+	// Parents waiting on their children keep waiting even if the simulation is aborted.
+	waitsOnChildren bool
+
+	parked bool
+}
+
+func (rec *waitRecord) isReady() bool {
+	if rec.ready != nil {
+		return rec.ready()
+	}
+	return rec.target.TickLowerBound().Cmp(rec.until) >= 0
+}
+
+// findDeadlock returns a report if none of the waiting contexts can ever be woken up.
+// A wait can complete if it is already ready, or if the context it waits on is still able to advance.
+func findDeadlock(waiting map[ContextView]*waitRecord) *DeadlockError {
+	if len(waiting) == 0 {
+		return nil
+	}
+	visiting := map[*waitRecord]bool{}
+	var canWake func(rec *waitRecord) bool
+	advances := func(ctx ContextView) bool {
+		if _, isParent := ctx.(ParentContext); isParent {
+			// Parents advance with their children, which are checked on their own.
+			return true
+		}
+		rec, isWaiting := waiting[ctx]
+		return !isWaiting || canWake(rec)
+	}
+	canWake = func(rec *waitRecord) bool {
+		if visiting[rec] {
+			return false
+		}
+		visiting[rec] = true
+		defer delete(visiting, rec)
+		return rec.isReady() || (rec.target != nil && advances(rec.target))
+	}
+
+	for _, rec := range waiting {
+		if canWake(rec) {
+			return nil
+		}
+	}
+	return deadlockReport(waiting)
+}
+
+func deadlockReport(waiting map[ContextView]*waitRecord) *DeadlockError {
+	report := new(DeadlockError)
+	for _, rec := range waiting {
+		if rec.waitsOnChildren {
+			// Parents waiting on their children aren't interesting.
+			continue
+		}
+		info := WaitInfo{
+			Context:    viewToString(rec.ctx),
+			LowerBound: rec.ctx.TickLowerBound(),
+			Until:      rec.until,
+		}
+		if rec.target != nil {
+			info.Target = viewToString(rec.target)
+		}
+		if rec.channel != nil {
+			info.Channel = rec.channel.endpointString()
+		}
+		report.Waits = append(report.Waits, info)
+	}
+	sort.Slice(report.Waits, func(i, j int) bool {
+		return report.Waits[i].Context < report.Waits[j].Context
+	})
+	return report
+}
+
+// WaitInfo describes a single stuck context in a DeadlockError.
+type WaitInfo struct {
+	Context    string
+	LowerBound *Time
+
+	// The channel being waited on, if any.
+	Channel string
+	// The context being waited on, and the time it needed to reach.
+	Target string
+	Until  *Time
+}
+
+func (info WaitInfo) String() string {
+	var what string
+	switch {
+	case info.Channel != "":
+		what = fmt.Sprintf("channel %s", info.Channel)
+	case info.Target != "":
+		what = info.Target
+	default:
+		what = "nothing"
+	}
+	if info.Until != nil {
+		return fmt.Sprintf("%s (time %v) waiting on %s until %v", info.Context, info.LowerBound, what, info.Until)
+	}
+	return fmt.Sprintf("%s (time %v) waiting on %s", info.Context, info.LowerBound, what)
+}
+
+// A DeadlockError is raised when every running context is waiting on another, so that none can make progress.
+type DeadlockError struct {
+	Waits []WaitInfo
+}
+
+func (err *DeadlockError) Error() string {
+	lines := []string{"deadlock: no context can make progress"}
+	for _, info := range err.Waits {
+		lines = append(lines, "\t"+info.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func viewToString(view ContextView) string {
+	if ctx, ok := view.(Context); ok {
+		return CtxToString(ctx)
+	}
+	return fmt.Sprintf("%T", view)
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestDeadlockDetection(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Bit](1)
+
+	var reader *SimpleNode[any]
+	// The writer waits for the reader to get far ahead, while the reader waits for the writer to catch up.
+	writer := &SimpleNode[any]{
+		RunFunc: func(node *SimpleNode[any]) {
+			WaitUntil(node, reader, NewTime(10))
+		},
+	}
+	reader = &SimpleNode[any]{
+		RunFunc: func(node *SimpleNode[any]) {
+			node.AdvanceToTime(NewTime(5))
+			node.InputChannel(0).Peek()
+		},
+	}
+	writer.AddOutputChannel(channel)
+	reader.AddInputChannel(channel)
+	ctx.AddChild(writer)
+	ctx.AddChild(reader)
+
+	ctx.Init()
+	defer (func() {
+		err, ok := recover().(*DeadlockError)
+		if !ok {
+			t.Fatalf("Expected a deadlock error")
+		}
+		t.Log(err)
+		if len(err.Waits) != 2 {
+			t.Fatalf("Expected both contexts to be reported, got %d", len(err.Waits))
+		}
+		report := err.Error()
+		for _, expected := range []string{"until 10", "until 5", channel.endpointString()} {
+			if !strings.Contains(report, expected) {
+				t.Errorf("Expected the report to contain %q", expected)
+			}
+		}
+	})()
+	ctx.Run()
+	t.Error("Run should not have returned")
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Topology describes a hierarchy of contexts and the channels between them, so that it can be exported.
+type Topology struct {
+	Root     TopologyNode      `json:"root"`
+	Channels []TopologyChannel `json:"channels"`
+}
+
+type TopologyNode struct {
+	// IDs are unique within the topology, unlike names.
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Children []TopologyNode `json:"children,omitempty"`
+}
+
+// A TopologyChannel connects two nodes by their IDs. From or To is empty if that end isn't connected.
+type TopologyChannel struct {
+	From     string `json:"from,omitempty"`
+	FromPort string `json:"fromPort,omitempty"`
+	To       string `json:"to,omitempty"`
+	ToPort   string `json:"toPort,omitempty"`
+	// Either a number of elements, Rendezvous, or Unbounded.
+	Capacity      int    `json:"capacity"`
+	InitialTokens int    `json:"initialTokens,omitempty"`
+	Type          string `json:"type"`
+}
+
+// DescribeTopology walks every context under root, along with the channels between them.
+func DescribeTopology(root ParentContext) (topology Topology) {
+	channels := map[*CommunicationChannel]int{}
+	channelFor := func(channel *CommunicationChannel) *TopologyChannel {
+		index, ok := channels[channel]
+		if !ok {
+			index = len(topology.Channels)
+			channels[channel] = index
+			topology.Channels = append(topology.Channels, TopologyChannel{
+				Capacity:      channel.capacity,
+				InitialTokens: channel.InitialTokens(),
+				Type:          channel.elemType,
+			})
+		}
+		return &topology.Channels[index]
+	}
+
+	nextID := 0
+	var describe func(ctx Context) TopologyNode
+	describe = func(ctx Context) TopologyNode {
+		node := TopologyNode{
+			ID:   fmt.Sprintf("n%d", nextID),
+			Name: ctxName(ctx),
+			Type: fmt.Sprintf("%T", ctx),
+		}
+		nextID++
+		if hasIO, ok := ctx.(hasLowLevelIO); ok {
+			llio := hasIO.lowLevelIO()
+			outputNames := portNames(llio.outputPorts)
+			for i, channel := range llio.outputChannels {
+				entry := channelFor(channel)
+				entry.From = node.ID
+				entry.FromPort = outputNames[i]
+			}
+			inputNames := portNames(llio.inputPorts)
+			for i, channel := range llio.inputChannels {
+				entry := channelFor(channel)
+				entry.To = node.ID
+				entry.ToPort = inputNames[i]
+			}
+		}
+		if parent, ok := ctx.(hasChildren); ok {
+			for _, child := range parent.Children() {
+				node.Children = append(node.Children, describe(child))
+			}
+		}
+		return node
+	}
+	topology.Root = describe(root)
+	return
+}
+
+// portNames maps channel indices back to the names of their ports.
+func portNames(ports map[string]int) map[int]string {
+	names := map[int]string{}
+	for name, id := range ports {
+		if id >= 0 {
+			names[id] = name
+		}
+	}
+	return names
+}
+
+func (topology *Topology) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(topology)
+}
+
+// WriteDOT writes the topology as a GraphViz graph, where each parent context is a cluster
+// and each channel is an edge labeled with its type and capacity.
+func (topology *Topology) WriteDOT(w io.Writer) error {
+	endpoints := map[string]bool{}
+	for _, channel := range topology.Channels {
+		endpoints[channel.From] = true
+		endpoints[channel.To] = true
+	}
+
+	var builder strings.Builder
+	var writeNode func(node TopologyNode, indent string)
+	writeNode = func(node TopologyNode, indent string) {
+		if len(node.Children) == 0 {
+			fmt.Fprintf(&builder, "%s%s [label=%s];\n", indent, node.ID, strconv.Quote(node.Name))
+			return
+		}
+		fmt.Fprintf(&builder, "%ssubgraph cluster_%s {\n%s\tlabel=%s;\n", indent, node.ID, indent, strconv.Quote(node.Name))
+		if endpoints[node.ID] {
+			// Edges can't attach to a cluster, so a parent with channels of its own gets a node inside of it.
+			fmt.Fprintf(&builder, "%s\t%s [label=%s, shape=point];\n", indent, node.ID, strconv.Quote(node.Name))
+		}
+		for _, child := range node.Children {
+			writeNode(child, indent+"\t")
+		}
+		fmt.Fprintf(&builder, "%s}\n", indent)
+	}
+
+	fmt.Fprintf(&builder, "digraph DAM {\n\tlabel=%s;\n\tnode [shape=box];\n", strconv.Quote(topology.Root.Name))
+	for _, child := range topology.Root.Children {
+		writeNode(child, "\t")
+	}
+	for i, channel := range topology.Channels {
+		from, to := channel.From, channel.To
+		// Dangling ends are drawn as points, so that they stand out.
+		if from == "" {
+			from = fmt.Sprintf("unconnected%d_src", i)
+			fmt.Fprintf(&builder, "\t%s [shape=point];\n", from)
+		}
+		if to == "" {
+			to = fmt.Sprintf("unconnected%d_dst", i)
+			fmt.Fprintf(&builder, "\t%s [shape=point];\n", to)
+		}
+		label := fmt.Sprintf("%s, %s", channel.Type, capacityString(channel.Capacity))
+		if channel.InitialTokens > 0 {
+			label = fmt.Sprintf("%s, %d initial tokens", label, channel.InitialTokens)
+		}
+		if channel.FromPort != "" || channel.ToPort != "" {
+			label = fmt.Sprintf("%s -> %s\n%s", channel.FromPort, channel.ToPort, label)
+		}
+		fmt.Fprintf(&builder, "\t%s -> %s [label=%s];\n", from, to, strconv.Quote(label))
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func capacityString(capacity int) string {
+	switch capacity {
+	case Rendezvous:
+		return "rendezvous"
+	case Unbounded:
+		return "unbounded"
+	}
+	return fmt.Sprintf("capacity %d", capacity)
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestExportTopology(t *testing.T) {
+	graph := MakeGraph()
+	noop := func(node *SimpleNode[any]) {}
+	producer := MakeSimpleNode(noop, (*any)(nil))
+	inner := MakePrimitiveContext(nil)
+	consumer := MakeSimpleNode(noop, (*any)(nil))
+	inner.AddChild(consumer)
+	graph.Add(producer, inner)
+	ConnectTyped[datatypes.Bit](graph, producer.Out("data"), consumer.In("vec"), 2)
+	// A channel wired by hand, which is missing its consumer
+	producer.AddOutputChannel(MakeCommunicationChannel[datatypes.FixedPoint](3))
+
+	topology := DescribeTopology(graph)
+	if len(topology.Root.Children) != 2 || len(topology.Root.Children[1].Children) != 1 {
+		t.Fatalf("Expected the nested context to be kept: %+v", topology.Root)
+	}
+	if len(topology.Channels) != 2 {
+		t.Fatalf("Expected 2 channels, got %+v", topology.Channels)
+	}
+	producerID := topology.Root.Children[0].ID
+	consumerID := topology.Root.Children[1].Children[0].ID
+	expected := TopologyChannel{From: producerID, FromPort: "data", To: consumerID, ToPort: "vec", Capacity: 2, Type: "datatypes.Bit"}
+	if topology.Channels[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, topology.Channels[0])
+	}
+
+	var dot bytes.Buffer
+	if err := topology.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(dot.String())
+	for _, expected := range []string{
+		"subgraph cluster_" + topology.Root.Children[1].ID,
+		producerID + " -> " + consumerID + ` [label="data -> vec\ndatatypes.Bit, capacity 2"]`,
+		"unconnected1_dst [shape=point]",
+		`label="datatypes.FixedPoint, capacity 3"`,
+	} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("Expected the DOT output to contain %q", expected)
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := topology.WriteJSON(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Topology
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Channels[1].To != "" || decoded.Root.Children[1].Children[0].Name != ctxName(consumer) {
+		t.Errorf("JSON did not round trip: %s", encoded.String())
+	}
+}
@@ -0,0 +1,265 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+	"github.com/stanford-ppl/DAM/utils"
+)
+
+// A Port is a named input or output of a node, as returned by In and Out.
+type Port struct {
+	io     *LowLevelIO
+	name   string
+	output bool
+}
+
+func (port Port) Name() string {
+	return port.name
+}
+
+func (port Port) direction() string {
+	if port.output {
+		return "output"
+	}
+	return "input"
+}
+
+type connection struct {
+	src, dst Port
+	channel  *CommunicationChannel
+}
+
+// A Graph is a root context whose children are wired together through named ports.
+// The wiring is checked before the graph is initialized, so that a dangling channel is reported
+// up front instead of failing in the middle of the simulation.
+type Graph struct {
+	basicContext
+
+	connections []connection
+}
+
+var _ ParentContext = (*Graph)(nil)
+
+func MakeGraph() *Graph {
+	return new(Graph)
+}
+
+func (graph *Graph) AddChild(child Context) {
+	graph.children = append(graph.children, child)
+	child.SetParent(graph)
+}
+
+// Add adds each of the nodes to the graph.
+func (graph *Graph) Add(nodes ...Context) {
+	for _, node := range nodes {
+		graph.AddChild(node)
+	}
+}
+
+// Connect creates a channel of the given depth from the output port src to the input port dst.
+// Mistakes such as connecting a port twice are reported by Validate.
+// The channel's elements can be of any type. Use ConnectTyped to say what they are.
+func (graph *Graph) Connect(src, dst Port, depth int, options ...ChannelOption) *CommunicationChannel {
+	return graph.connect(src, dst, MakeCommunicationChannel[datatypes.DAMType](depth, options...))
+}
+
+// ConnectTyped is like Connect, but the channel carries elements of type T, which shows up when the graph is
+// exported or traced.
+func ConnectTyped[T datatypes.DAMType](graph *Graph, src, dst Port, depth int, options ...ChannelOption) *CommunicationChannel {
+	return graph.connect(src, dst, MakeCommunicationChannel[T](depth, options...))
+}
+
+func (graph *Graph) connect(src, dst Port, channel *CommunicationChannel) *CommunicationChannel {
+	graph.connections = append(graph.connections, connection{src: src, dst: dst, channel: channel})
+	if src.io == nil || dst.io == nil || !src.output || dst.output {
+		return channel
+	}
+	// Only wire up ports which are still free, so that a mistake doesn't leave a half-connected channel behind.
+	if src.io.outputPorts[src.name] < 0 && dst.io.inputPorts[dst.name] < 0 {
+		src.io.outputPorts[src.name] = src.io.AddOutputChannel(channel)
+		dst.io.inputPorts[dst.name] = dst.io.AddInputChannel(channel)
+	}
+	return channel
+}
+
+// Validate checks that every port is connected exactly once, that every channel of every node in the graph
+// has exactly one producer and one consumer within it, and that every cycle of channels has initial tokens
+// somewhere along it. All of the problems found are returned together.
+func (graph *Graph) Validate() error {
+	var errs []error
+	// Walk the graph, so that we know which node each set of ports belongs to.
+	var order []*LowLevelIO
+	owners := map[*LowLevelIO]Context{}
+	for _, child := range graph.children {
+		walkContexts(child, func(ctx Context) {
+			if hasIO, ok := ctx.(hasLowLevelIO); ok {
+				if _, seen := owners[hasIO.lowLevelIO()]; !seen {
+					order = append(order, hasIO.lowLevelIO())
+					owners[hasIO.lowLevelIO()] = ctx
+				}
+			}
+		})
+	}
+	portString := func(port Port) string {
+		if owner, ok := owners[port.io]; ok {
+			return fmt.Sprintf("%s.%s", CtxToString(owner), port.name)
+		}
+		return port.name
+	}
+
+	uses := map[Port]int{}
+	var used []Port
+	for _, conn := range graph.connections {
+		for _, end := range []struct {
+			port   Port
+			output bool
+		}{{conn.src, true}, {conn.dst, false}} {
+			switch {
+			case end.port.io == nil:
+				errs = append(errs, fmt.Errorf("connection %s -> %s uses an undeclared port", portString(conn.src), portString(conn.dst)))
+			case end.port.output != end.output:
+				errs = append(errs, fmt.Errorf("port %s is an %s, but was connected as an %s", portString(end.port), end.port.direction(), Port{output: end.output}.direction()))
+			case owners[end.port.io] == nil:
+				errs = append(errs, fmt.Errorf("port %s belongs to a node which isn't in the graph", portString(end.port)))
+			default:
+				if uses[end.port] == 0 {
+					used = append(used, end.port)
+				}
+				uses[end.port]++
+			}
+		}
+	}
+	for _, port := range used {
+		if uses[port] > 1 {
+			errs = append(errs, fmt.Errorf("%s port %s is connected %d times", port.direction(), portString(port), uses[port]))
+		}
+	}
+
+	// Channels can also be wired up by hand, so check every channel of every node.
+	producers := map[*CommunicationChannel][]string{}
+	consumers := map[*CommunicationChannel][]string{}
+	srcs := map[*CommunicationChannel]*LowLevelIO{}
+	dsts := map[*CommunicationChannel]*LowLevelIO{}
+	var channels []*CommunicationChannel
+	for _, io := range order {
+		for _, port := range sortedPorts(io.inputPorts) {
+			if io.inputPorts[port] < 0 {
+				errs = append(errs, fmt.Errorf("input port %s is not connected", portString(Port{io: io, name: port})))
+			}
+		}
+		for _, port := range sortedPorts(io.outputPorts) {
+			if io.outputPorts[port] < 0 {
+				errs = append(errs, fmt.Errorf("output port %s is not connected", portString(Port{io: io, name: port, output: true})))
+			}
+		}
+		for _, channel := range io.inputChannels {
+			if len(producers[channel])+len(consumers[channel]) == 0 {
+				channels = append(channels, channel)
+			}
+			consumers[channel] = append(consumers[channel], CtxToString(owners[io]))
+			dsts[channel] = io
+		}
+		for _, channel := range io.outputChannels {
+			if len(producers[channel])+len(consumers[channel]) == 0 {
+				channels = append(channels, channel)
+			}
+			producers[channel] = append(producers[channel], CtxToString(owners[io]))
+			srcs[channel] = io
+		}
+	}
+	for _, channel := range channels {
+		if len(producers[channel]) != 1 || len(consumers[channel]) != 1 {
+			errs = append(errs, fmt.Errorf("channel needs exactly one producer and one consumer, but has producers [%s] and consumers [%s]",
+				strings.Join(producers[channel], ", "), strings.Join(consumers[channel], ", ")))
+		}
+	}
+
+	// A loop can only get going if some channel in it starts out with tokens.
+	next := map[*LowLevelIO][]*LowLevelIO{}
+	for _, channel := range channels {
+		if len(producers[channel]) == 1 && len(consumers[channel]) == 1 && channel.InitialTokens() == 0 {
+			next[srcs[channel]] = append(next[srcs[channel]], dsts[channel])
+		}
+	}
+	for _, cycle := range findCycles(order, next) {
+		names := utils.Map(cycle, func(io *LowLevelIO) string { return CtxToString(owners[io]) })
+		errs = append(errs, fmt.Errorf("cycle through [%s] has no channel with initial tokens", strings.Join(names, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// findCycles returns each group of nodes which can reach each other through next, in the order they are first visited.
+// A single node is only a cycle if it is connected to itself.
+func findCycles(order []*LowLevelIO, next map[*LowLevelIO][]*LowLevelIO) (cycles [][]*LowLevelIO) {
+	// Tarjan's algorithm for strongly connected components
+	index := map[*LowLevelIO]int{}
+	lowLink := map[*LowLevelIO]int{}
+	onStack := map[*LowLevelIO]bool{}
+	var stack []*LowLevelIO
+	var visit func(io *LowLevelIO)
+	visit = func(io *LowLevelIO) {
+		index[io] = len(index)
+		lowLink[io] = index[io]
+		stack = append(stack, io)
+		onStack[io] = true
+		selfLoop := false
+		for _, succ := range next[io] {
+			selfLoop = selfLoop || succ == io
+			if _, seen := index[succ]; !seen {
+				visit(succ)
+				if lowLink[succ] < lowLink[io] {
+					lowLink[io] = lowLink[succ]
+				}
+			} else if onStack[succ] && index[succ] < lowLink[io] {
+				lowLink[io] = index[succ]
+			}
+		}
+		if lowLink[io] != index[io] {
+			return
+		}
+		var component []*LowLevelIO
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == io {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			// The component was popped in reverse.
+			for i, j := 0, len(component)-1; i < j; i, j = i+1, j-1 {
+				component[i], component[j] = component[j], component[i]
+			}
+			cycles = append(cycles, component)
+		}
+	}
+	for _, io := range order {
+		if _, seen := index[io]; !seen {
+			visit(io)
+		}
+	}
+	return
+}
+
+// Init validates the graph before initializing its nodes, and panics if it isn't valid.
+// Simulate validates the graph first, and returns the problems as an error instead.
+func (graph *Graph) Init() {
+	if err := graph.Validate(); err != nil {
+		panic(err)
+	}
+	graph.basicContext.Init()
+}
+
+func sortedPorts(ports map[string]int) (names []string) {
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
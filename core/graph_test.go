@@ -0,0 +1,77 @@
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestGraphConnect(t *testing.T) {
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+	graph := MakeGraph()
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			out := node.OutputID("data")
+			AdvanceUntilCanEnqueue(node, out)
+			val := datatypes.FixedPoint{Tp: fpt}
+			val.SetInt(big.NewInt(int64(i)))
+			node.OutputChannel(out).Enqueue(MakeChannelElement(node.TickLowerBound(), val))
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	var received []int64
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			elem := DequeueInputChansByID(node, node.InputID("vec"))[0]
+			received = append(received, elem.Data.(datatypes.FixedPoint).ToInt().Int64())
+		}
+	}, (*any)(nil))
+	graph.Add(producer, consumer)
+	graph.Connect(producer.Out("data"), consumer.In("vec"), 2)
+
+	if _, err := Simulate(graph); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 4 || received[3] != 3 {
+		t.Errorf("Expected 0 through 3, received %v", received)
+	}
+}
+
+func TestGraphValidation(t *testing.T) {
+	graph := MakeGraph()
+	noop := func(node *SimpleNode[any]) {}
+	producer := MakeSimpleNode(noop, (*any)(nil))
+	consumer := MakeSimpleNode(noop, (*any)(nil))
+	outsider := MakeSimpleNode(noop, (*any)(nil))
+	graph.Add(producer, consumer)
+
+	graph.Connect(producer.Out("data"), consumer.In("vec"), 2)
+	graph.Connect(producer.Out("data"), consumer.In("mat"), 2)
+	graph.Connect(consumer.In("vec"), producer.Out("other"), 2)
+	graph.Connect(outsider.Out("data"), consumer.In("extra"), 2)
+	producer.Out("unused")
+	// A channel wired by hand, which is missing its consumer
+	producer.AddOutputChannel(MakeCommunicationChannel[datatypes.Bit](1))
+
+	_, err := Simulate(graph)
+	if err == nil {
+		t.Fatal("Expected the graph to be invalid")
+	}
+	t.Log(err)
+	report := err.Error()
+	for _, expected := range []string{
+		"output port " + CtxToString(producer) + ".data is connected 2 times",
+		"port " + CtxToString(consumer) + ".vec is an input, but was connected as an output",
+		"port data belongs to a node which isn't in the graph",
+		"output port " + CtxToString(producer) + ".unused is not connected",
+		"input port " + CtxToString(consumer) + ".mat is not connected",
+		"producers [" + CtxToString(producer) + "] and consumers []",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("Expected the report to contain %q", expected)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// A ChannelOption configures a channel made by MakeCommunicationChannel.
+type ChannelOption func(*CommunicationChannel)
+
+// WithWireLatency delays every element by the given number of the source's cycles on its way to the destination.
+// Since the destination knows that nothing can arrive sooner, it also acts as lookahead.
+func WithWireLatency(cycles int64) ChannelOption {
+	if cycles < 0 {
+		panic(fmt.Sprintf("A channel's wire latency can't be negative, got %d", cycles))
+	}
+	return func(cchan *CommunicationChannel) {
+		cchan.wireLatency = cycles
+	}
+}
+
+// WithBandwidth limits the channel to sending bitsPerCycle bits each of the source's cycles.
+// Each element then occupies the link for ceil(Size() / bitsPerCycle) cycles, starting once it is ready and the
+// previous element is through, so elements sent back to back queue up behind each other.
+func WithBandwidth(bitsPerCycle int64) ChannelOption {
+	if bitsPerCycle <= 0 {
+		panic(fmt.Sprintf("A channel's bandwidth must be positive, got %d", bitsPerCycle))
+	}
+	return func(cchan *CommunicationChannel) {
+		cchan.bandwidth = bitsPerCycle
+	}
+}
+
+// transitTime is how long the wire latency takes, in the source's clock.
+func (cchan *CommunicationChannel) transitTime() *Time {
+	return clockDomainOf(cchan.srcCtx).CyclesToTime(NewTime(cchan.wireLatency))
+}
+
+// transmit stamps ce with when it reaches the end of the link, if it is ready to be sent at ce.Time.
+// The element first waits for the link to be free, then takes its share of the bandwidth to cross,
+// and arrives once the wire latency has passed after that.
+func (cchan *CommunicationChannel) transmit(ce *ChannelElement) {
+	if cchan.bandwidth == 0 {
+		ce.Time.Add(&ce.Time, cchan.transitTime())
+		return
+	}
+	start := &ce.Time
+	if cchan.linkFree.Cmp(start) > 0 {
+		start = &cchan.linkFree
+	}
+	var cycles big.Int
+	if ce.Data != nil {
+		bandwidth := big.NewInt(cchan.bandwidth)
+		cycles.Add(ce.Data.Size(), bandwidth)
+		cycles.Sub(&cycles, big.NewInt(1))
+		cycles.Quo(&cycles, bandwidth)
+	}
+	occupancy := clockDomainOf(cchan.srcCtx).CyclesToTime(NewTime(cycles.Int64()))
+	cchan.linkFree.Add(start, occupancy)
+	ce.Time.Add(&cchan.linkFree, cchan.transitTime())
+}
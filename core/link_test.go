@@ -0,0 +1,60 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Sends values at each of the send times, and returns when they were received.
+func runLink(t *testing.T, backend Backend, domain *ClockDomain, sendTimes []int64, options ...ChannelOption) (received []int64) {
+	channel := MakeCommunicationChannel[datatypes.FixedPoint](len(sendTimes), options...)
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for _, sendTime := range sendTimes {
+			node.AdvanceToTime(NewTime(sendTime))
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.FixedPoint{Tp: fpt}))
+		}
+	}, (*any)(nil))
+	producer.SetClockDomain(domain)
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for {
+			input := DequeueInputChansByID(node, 0)[0]
+			if input.Status == Closed {
+				return
+			}
+			time := input.Time.GetTime()
+			received = append(received, time.Int64())
+		}
+	}, (*any)(nil))
+	runPair(t, backend, channel, producer, consumer)
+	return
+}
+
+// checkArrivals runs the link with each backend, and checks when the values were received.
+func checkArrivals(t *testing.T, expected []int64, domain *ClockDomain, sendTimes []int64, options ...ChannelOption) {
+	t.Helper()
+	for _, backend := range backends {
+		got := runLink(t, backend, domain, sendTimes, options...)
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("Expected arrivals at %v with the %v backend, got %v", expected, backend, got)
+		}
+	}
+}
+
+func TestWireLatency(t *testing.T) {
+	checkArrivals(t, []int64{3, 13, 23}, nil, []int64{0, 10, 20}, WithWireLatency(3))
+}
+
+func TestBandwidthContention(t *testing.T) {
+	// Each 32 bit element takes 2 cycles to get onto the link, so the ones sent together queue up.
+	checkArrivals(t, []int64{5, 7, 9, 15}, nil, []int64{0, 0, 0, 10}, WithWireLatency(3), WithBandwidth(16))
+	// Partial cycles are rounded up.
+	checkArrivals(t, []int64{3, 6, 9}, nil, []int64{0, 0, 0}, WithBandwidth(12))
+}
+
+func TestLinkUsesSourceClock(t *testing.T) {
+	checkArrivals(t, []int64{20, 30}, &ClockDomain{Name: "slow", Period: 10}, []int64{0, 0}, WithWireLatency(1), WithBandwidth(32))
+}
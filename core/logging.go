@@ -1,27 +1,163 @@
 package core
 
 import (
+	"bytes"
+	"os"
+	"strings"
 	"sync"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// A LogEncoding is how each log line is written.
+type LogEncoding uint8
+
+const (
+	JSONEncoding LogEncoding = iota
+	// Human readable lines, as in zap's development config
+	ConsoleEncoding
+)
+
+func (encoding LogEncoding) String() string {
+	switch encoding {
+	case JSONEncoding:
+		return "JSON"
+	case ConsoleEncoding:
+		return "Console"
+	}
+	return "X"
+}
+
+// LoggingConfig controls the loggers returned by GetLogger.
+type LoggingConfig struct {
+	// The lowest level that is logged, unless overridden by ContextLevels
+	Level zapcore.Level
+	// Levels for particular contexts and everything below them, keyed by their CtxToString path.
+	// The longest matching path wins.
+	ContextLevels map[string]zapcore.Level
+	// Where log lines go. Nil discards them.
+	Output   zapcore.WriteSyncer
+	Encoding LogEncoding
+}
+
+// DefaultLoggingConfig logs info and above to stderr as JSON, like zap.NewProduction.
+func DefaultLoggingConfig() LoggingConfig {
+	return LoggingConfig{Level: zapcore.InfoLevel, Output: StderrSink(), Encoding: JSONEncoding}
+}
+
+func StderrSink() zapcore.WriteSyncer {
+	return zapcore.Lock(os.Stderr)
+}
+
+// FileSink appends log lines to the file at path, creating it if needed.
+func FileSink(path string) (zapcore.WriteSyncer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return zapcore.Lock(file), nil
+}
+
+// A MemorySink keeps log lines in memory, so that tests can check them.
+type MemorySink struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (sink *MemorySink) Write(p []byte) (int, error) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.buffer.Write(p)
+}
+
+func (sink *MemorySink) Sync() error {
+	return nil
+}
+
+func (sink *MemorySink) String() string {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	return sink.buffer.String()
+}
+
+// Lines returns each line logged so far.
+func (sink *MemorySink) Lines() []string {
+	text := strings.TrimSuffix(sink.String(), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
 var (
-	smap       map[Context]*zap.Logger
-	smapMutex  sync.RWMutex
-	smapInit   sync.Once
-	logOptions []zap.Option
+	smap          map[Context]*zap.Logger
+	smapMutex     sync.RWMutex
+	loggingConfig = DefaultLoggingConfig()
 )
 
-func initSmap() {
-	smapInit.Do(func() {
-		smap = map[Context]*zap.Logger{}
-		logOptions = append(logOptions, zap.AddCaller())
-	})
+// ConfigureLogging replaces the config used by GetLogger.
+// Loggers that were already handed out keep using the config they were built with.
+func ConfigureLogging(config LoggingConfig) {
+	smapMutex.Lock()
+	defer smapMutex.Unlock()
+	loggingConfig = config
+	smap = nil
+}
+
+// levelFor finds the level for the context at path.
+func (config *LoggingConfig) levelFor(path string) zapcore.Level {
+	level, matched := config.Level, -1
+	for prefix, prefixLevel := range config.ContextLevels {
+		if (path == prefix || strings.HasPrefix(path, prefix+".")) && len(prefix) > matched {
+			level, matched = prefixLevel, len(prefix)
+		}
+	}
+	return level
+}
+
+func (config *LoggingConfig) build(ctx Context) *zap.Logger {
+	if config.Output == nil {
+		return zap.NewNop()
+	}
+	name := CtxToString(ctx)
+	var encoder zapcore.Encoder
+	switch config.Encoding {
+	case ConsoleEncoding:
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	}
+	core := zapcore.NewCore(encoder, config.Output, config.levelFor(name))
+	return zap.New(timedCore{core, ctx}, zap.AddCaller()).Named(name)
+}
+
+// A timedCore adds the simulated time of its context to every entry.
+type timedCore struct {
+	zapcore.Core
+	ctx Context
+}
+
+func (core timedCore) With(fields []zapcore.Field) zapcore.Core {
+	return timedCore{core.Core.With(fields), core.ctx}
+}
+
+func (core timedCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+	return checked
+}
+
+func (core timedCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	withTime := make([]zapcore.Field, len(fields), len(fields)+1)
+	copy(withTime, fields)
+	return core.Core.Write(entry, append(withTime, zap.Stringer("sim_time", core.ctx.TickLowerBound())))
 }
 
+// GetLogger returns the logger for ctx, which is named after its path and includes its TickLowerBound
+// in every line as sim_time.
 func GetLogger(ctx Context) *zap.Logger {
-	initSmap()
 	smapMutex.RLock()
 	{
 		// Check if there's a logger already. If so, just return it.
@@ -41,7 +177,10 @@ func GetLogger(ctx Context) *zap.Logger {
 			return v
 		}
 	}
-	l, _ := zap.NewProduction(logOptions...)
-	smap[ctx] = l.Named(CtxToString(ctx))
+	if smap == nil {
+		smap = map[Context]*zap.Logger{}
+	}
+	l := loggingConfig.build(ctx)
+	smap[ctx] = l
 	return l
 }
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggingLevelsAndTime(t *testing.T) {
+	sink := new(MemorySink)
+	ctx := MakePrimitiveContext(nil)
+	quiet := MakeSimpleNode(func(node *SimpleNode[any]) {
+		GetLogger(node).Info("filtered")
+		GetLogger(node).Warn("kept")
+	}, (*any)(nil))
+	verbose := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.IncrCycles(NewTime(5))
+		GetLogger(node).Debug("debugging")
+	}, (*any)(nil))
+	ctx.AddChild(quiet)
+	ctx.AddChild(verbose)
+
+	ConfigureLogging(LoggingConfig{
+		Level:         zapcore.WarnLevel,
+		ContextLevels: map[string]zapcore.Level{CtxToString(verbose): zapcore.DebugLevel},
+		Output:        sink,
+	})
+	defer ConfigureLogging(DefaultLoggingConfig())
+	if _, err := Simulate(ctx, WithBackend(DeterministicBackend)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := sink.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %v", lines)
+	}
+	expected := map[string]map[string]any{
+		"kept":      {"logger": CtxToString(quiet), "sim_time": "0"},
+		"debugging": {"logger": CtxToString(verbose), "sim_time": "5"},
+	}
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		fields, ok := expected[entry["msg"].(string)]
+		if !ok {
+			t.Errorf("Unexpected line %s", line)
+			continue
+		}
+		for key, value := range fields {
+			if entry[key] != value {
+				t.Errorf("Expected %s to be %v, got %s", key, value, line)
+			}
+		}
+	}
+}
+
+func TestLoggingConsoleEncoding(t *testing.T) {
+	sink := new(MemorySink)
+	ConfigureLogging(LoggingConfig{Level: zapcore.InfoLevel, Output: sink, Encoding: ConsoleEncoding})
+	defer ConfigureLogging(DefaultLoggingConfig())
+	node := MakeSimpleNode(func(node *SimpleNode[any]) {}, (*any)(nil))
+	node.IncrCycles(NewTime(3))
+	GetLogger(node).Info("hello")
+	if line := sink.String(); !strings.Contains(line, "hello") || !strings.Contains(line, `"sim_time": "3"`) {
+		t.Errorf("Expected a console line with the time, got %q", line)
+	}
+}
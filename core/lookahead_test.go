@@ -0,0 +1,119 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Sends a few elements far apart, and counts how many times the reader comes up empty while waiting for them.
+func runSparseChannel(t *testing.T, backend Backend, channelLookahead, nodeLookahead int64) (emptyPeeks int, received []*Time) {
+	channel := MakeCommunicationChannel[datatypes.Bit](4).SetLookahead(NewTime(channelLookahead))
+
+	var consumer *SimpleNode[any]
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			sendTime := node.TickLowerBound()
+			sendTime.Add(sendTime, NewTime(100))
+			node.OutputChannel(0).Enqueue(MakeChannelElement(sendTime, datatypes.Bit{}))
+			// Idle one tick at a time, without getting ahead of the consumer.
+			for tick := 0; tick < 1000; tick++ {
+				node.IncrCycles(OneTick)
+				WaitUntil(node, consumer, node.TickLowerBound())
+			}
+		}
+	}, (*any)(nil))
+	producer.SetLookahead(NewTime(nodeLookahead))
+	consumer = MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			for {
+				elem, status := node.InputChannel(0).Dequeue()
+				if status != Nothing {
+					received = append(received, new(Time).Set(&elem.Time))
+					break
+				}
+				emptyPeeks++
+				node.AdvanceToTime(&elem.Time)
+				node.IncrCycles(OneTick)
+			}
+		}
+	}, (*any)(nil))
+	runPair(t, backend, channel, producer, consumer)
+	return
+}
+
+func TestLookaheadSkipsAhead(t *testing.T) {
+	for _, backend := range backends {
+		baselinePeeks, baseline := runSparseChannel(t, backend, 0, 0)
+		for _, lookahead := range [][2]int64{{100, 0}, {0, 100}, {50, 100}} {
+			peeks, received := runSparseChannel(t, backend, lookahead[0], lookahead[1])
+			if len(received) != len(baseline) {
+				t.Fatalf("%v: expected %d elements, got %d", backend, len(baseline), len(received))
+			}
+			for i := range received {
+				if received[i].Cmp(baseline[i]) != 0 {
+					t.Errorf("%v: lookahead %v: element %d was received at %v instead of %v", backend, lookahead, i, received[i], baseline[i])
+				}
+			}
+			// How far the reader can skip depends on how far along the writer is when it looks, so the number of
+			// empty peeks is only reproducible when the contexts are run in a fixed order.
+			if backend != DeterministicBackend {
+				continue
+			}
+			// Without lookahead, the reader steps through every tick between elements.
+			if peeks*10 > baselinePeeks {
+				t.Errorf("Lookahead %v: expected far fewer than %d empty peeks, got %d", lookahead, baselinePeeks, peeks)
+			}
+		}
+	}
+}
+
+func TestLookaheadViolation(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Bit](1).SetLookahead(NewTime(10))
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.OutputChannel(0).Enqueue(MakeChannelElement(NewTime(5), datatypes.Bit{}))
+	}, (*any)(nil))
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		DequeueInputChansByID(node, 0)
+	}, (*any)(nil))
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	_, err := Simulate(ctx)
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Context != CtxToString(producer) {
+		t.Fatalf("Expected the producer to fail, got %v", err)
+	}
+}
+
+// A node's lookahead is in its own cycles, so it is stretched by a slower clock.
+func TestLookaheadUsesClockDomain(t *testing.T) {
+	for _, sendTime := range []int64{20, 30} {
+		channel := MakeCommunicationChannel[datatypes.Bit](1)
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(NewTime(sendTime), datatypes.Bit{}))
+		}, (*any)(nil))
+		producer.SetClockDomain(&ClockDomain{Name: "slow", Period: 10})
+		producer.SetLookahead(NewTime(3))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			DequeueInputChansByID(node, 0)
+		}, (*any)(nil))
+		if lookahead := producer.Lookahead(); lookahead.Cmp(NewTime(30)) != 0 {
+			t.Errorf("Expected a lookahead of 3 cycles to be 30, got %v", lookahead)
+		}
+		ctx := MakePrimitiveContext(nil)
+		producer.AddOutputChannel(channel)
+		consumer.AddInputChannel(channel)
+		ctx.AddChild(producer)
+		ctx.AddChild(consumer)
+		_, err := Simulate(ctx)
+		if violated := err != nil; violated != (sendTime < 30) {
+			t.Errorf("Sending at %d: expected a violation to be %v, got %v", sendTime, sendTime < 30, err)
+		}
+	}
+}
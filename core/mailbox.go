@@ -0,0 +1,75 @@
+package core
+
+import "sync"
+
+// A mailbox holds the elements sent on a channel until the destination takes them, in a ring buffer.
+// Unlike a Go channel it never fills up, since the channel itself keeps track of its capacity in simulated time,
+// and an unbounded channel doesn't have one. The destination can also look past the element at the front.
+type mailbox struct {
+	mutex sync.Mutex
+	// The elements are items[start], items[start+1], ..., wrapping around, count of them in all.
+	items  []ChannelElement
+	start  int
+	count  int
+	closed bool
+
+	// Holds a value whenever something has been sent or the mailbox was closed since it was last drained.
+	// A receiver waiting on it must still check for what it's after, since the value may be for something older.
+	arrived chan struct{}
+}
+
+func makeMailbox(size int) *mailbox {
+	if size < 1 {
+		size = 1
+	}
+	return &mailbox{items: make([]ChannelElement, size), arrived: make(chan struct{}, 1)}
+}
+
+func (box *mailbox) notify() {
+	select {
+	case box.arrived <- struct{}{}:
+	default:
+	}
+}
+
+func (box *mailbox) send(ce ChannelElement) {
+	box.mutex.Lock()
+	if box.count == len(box.items) {
+		grown := make([]ChannelElement, 2*len(box.items))
+		n := copy(grown, box.items[box.start:])
+		copy(grown[n:], box.items[:box.start])
+		box.items, box.start = grown, 0
+	}
+	box.items[(box.start+box.count)%len(box.items)] = ce
+	box.count++
+	box.mutex.Unlock()
+	box.notify()
+}
+
+func (box *mailbox) close() {
+	box.mutex.Lock()
+	box.closed = true
+	box.mutex.Unlock()
+	box.notify()
+}
+
+// at returns the element i places from the front of the mailbox, without waiting.
+// Like receiving from a Go channel, ok is false if the mailbox is closed before then.
+// If it hasn't been sent yet, received is false.
+func (box *mailbox) at(i int) (ce ChannelElement, ok bool, received bool) {
+	box.mutex.Lock()
+	defer box.mutex.Unlock()
+	if i >= box.count {
+		return ce, false, box.closed
+	}
+	return box.items[(box.start+i)%len(box.items)], true, true
+}
+
+// remove drops the element at the front of the mailbox.
+func (box *mailbox) remove() {
+	box.mutex.Lock()
+	defer box.mutex.Unlock()
+	box.items[box.start] = ChannelElement{}
+	box.start = (box.start + 1) % len(box.items)
+	box.count--
+}
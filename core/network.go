@@ -2,7 +2,11 @@ package core
 
 import (
 	"fmt"
+	"math"
+	"reflect"
+	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/stanford-ppl/DAM/datatypes"
 	"github.com/stanford-ppl/DAM/utils"
@@ -11,6 +15,8 @@ import (
 type ChannelElement struct {
 	Time Time
 	Data datatypes.DAMType
+	// Updates for the tags of the receiving node, keyed by the name of their TagType.
+	Tags map[string]any
 }
 
 type Status uint8
@@ -50,7 +56,7 @@ func (stat Status) String() string {
 
 // This needs to be public because we need it for network sim.
 type CommunicationChannel struct {
-	underlying chan ChannelElement
+	underlying *mailbox
 	resp       chan *Time
 
 	capacityMutex sync.RWMutex
@@ -59,21 +65,92 @@ type CommunicationChannel struct {
 	capacity      int
 	nextTime      *Time
 
-	// To support peeking
-	headStatus Status
-	head       *ChannelElement
+	// The minimum latency of the channel, on top of the lookahead of the source.
+	minLatency Time
+
+	// The name of the type of data carried by the channel.
+	elemType string
+
+	// The number of destination cycles taken to cross between clock domains
+	synchronizer int64
+
+	// The number of source cycles every element takes to cross the channel
+	wireLatency int64
+	// How many bits the channel carries each source cycle, or 0 if it isn't limited
+	bandwidth int64
+	// When the last element sent will have finished occupying the link.
+	// This is only touched by the source.
+	linkFree Time
+
+	// Only set once EnableStats is called.
+	stats *channelStats
+	// Only set once the channel is traced.
+	trace *channelTrace
+	// Only set once the channel is recorded.
+	record *channelLog
+	// Only set while the simulation the channel is in is being traced.
+	tracer *ChromeTracer
+
+	// How many times the channel has been written to, including closing it.
+	// Unlike the length of underlying, this never goes down, so a waiting reader can't miss a write.
+	writes atomic.Int64
+	// How many elements the source has sent, and how many of them the destination has taken.
+	// Only the source touches sent.
+	sent  int64
+	taken atomic.Int64
+
+	// What the channel starts out holding, which is sent once either end first uses it.
+	initialTokens []ChannelElement
+	initialOnce   sync.Once
+
+	// Nothing else the source sends can arrive at or before this time, if it is set.
+	// Only the destination touches it.
+	quietUntil *Time
 
 	srcCtx ContextView
 	dstCtx ContextView
 }
 
 func (cchan *CommunicationChannel) String() string {
-	return fmt.Sprintf("%+v --> %v --> %+v (capacity = %d)", cchan.srcCtx, cchan.underlying, cchan.dstCtx, cchan.capacity)
+	return fmt.Sprintf("%+v --> %s --> %+v (capacity = %d)", cchan.srcCtx, cchan.elemType, cchan.dstCtx, cchan.capacity)
+}
+
+// endpointString names the contexts on either end of the channel.
+func (cchan *CommunicationChannel) endpointString() string {
+	return fmt.Sprintf("%s -> %s", viewToString(cchan.srcCtx), viewToString(cchan.dstCtx))
+}
+
+// A HasLookahead context promises that everything it sends is stamped at least Lookahead() past its current time.
+type HasLookahead interface {
+	Lookahead() *Time
+}
+
+// SetLookahead declares that every element sent on the channel is stamped at least lookahead past the sender's time.
+// A reader which finds the channel empty can then skip ahead by that much, instead of stepping one tick at a time.
+func (cchan *CommunicationChannel) SetLookahead(lookahead *Time) *CommunicationChannel {
+	cchan.minLatency.Set(lookahead)
+	return cchan
+}
+
+// sourceLookahead combines the lookahead of the channel and of its source.
+func (cchan *CommunicationChannel) sourceLookahead() *Time {
+	result := new(Time).Set(&cchan.minLatency)
+	if src, ok := cchan.srcCtx.(HasLookahead); ok {
+		utils.Max[*Time](src.Lookahead(), result, result)
+	}
+	return result
+}
+
+// lookahead is how far past the source's time anything it sends arrives, including the wire latency.
+func (cchan *CommunicationChannel) lookahead() *Time {
+	result := cchan.sourceLookahead()
+	return result.Add(result, cchan.transitTime())
 }
 
 type OutputChannel interface {
 	// Enqueue returns a (success, nextAvailable) pair
 	// nextAvailable may be nil if it's not clear when it'll become available
+	// On a Rendezvous channel, a successful Enqueue waits for the destination, and returns when it took the element.
 	Enqueue(ChannelElement) (bool, *Time)
 
 	// IsFull returns if the channel is full from the srcCtx perspective
@@ -92,7 +169,9 @@ func (cchan *CommunicationChannel) incrSRDelta(amt int) {
 }
 
 func (cchan *CommunicationChannel) CloseOutput() {
-	close(cchan.underlying)
+	cchan.sendInitialTokens()
+	cchan.writes.Add(1)
+	cchan.underlying.close()
 }
 
 func (cchan *CommunicationChannel) updateLen() {
@@ -108,9 +187,21 @@ func (cchan *CommunicationChannel) updateLen() {
 		cchan.nextTime = nil
 	}
 	waitChan := cchan.dstCtx.BlockUntil(updateTime)
+	sim := simulationOf(cchan.srcCtx)
+	rec := &waitRecord{
+		ctx:     cchan.srcCtx,
+		target:  cchan.dstCtx,
+		until:   updateTime,
+		channel: cchan,
+		ready: func() bool {
+			return len(cchan.resp) > 0 || cchan.dstCtx.TickLowerBound().Cmp(updateTime) >= 0
+		},
+	}
 	for {
+		abort := sim.park(rec)
 		select {
 		case <-waitChan:
+			sim.unpark(rec)
 			// we're up to date, just need to flush the channel
 			for {
 				select {
@@ -126,6 +217,7 @@ func (cchan *CommunicationChannel) updateLen() {
 				}
 			}
 		case time := <-cchan.resp:
+			sim.unpark(rec)
 			// we've received a time on the response channel
 			if updateTime.Cmp(time) < 0 {
 				// we're done for now.
@@ -133,6 +225,9 @@ func (cchan *CommunicationChannel) updateLen() {
 				return
 			}
 			cchan.sendRecvDelta--
+		case <-abort:
+			sim.unpark(rec)
+			runtime.Goexit()
 		}
 	}
 }
@@ -140,8 +235,11 @@ func (cchan *CommunicationChannel) updateLen() {
 // Note that IsFull also waits for the destination to catch up if it might be full!
 // This means that if IsFull() is true, then the destination is also up to date with the source.
 func (cchan *CommunicationChannel) IsFull() bool {
+	if cchan.capacity == Unbounded {
+		return false
+	}
 	cchan.capacityMutex.RLock()
-	if cchan.sendRecvDelta < cchan.capacity {
+	if cchan.sendRecvDelta < cchan.slots() {
 		cchan.capacityMutex.RUnlock()
 		return false
 	}
@@ -150,7 +248,16 @@ func (cchan *CommunicationChannel) IsFull() bool {
 	// Now that we've updated capacity, re-check
 	cchan.capacityMutex.RLock()
 	defer cchan.capacityMutex.RUnlock()
-	return cchan.sendRecvDelta == cchan.capacity
+	full := cchan.sendRecvDelta == cchan.slots()
+	if full && cchan.stats != nil {
+		cchan.stats.recordFull(cchan.srcCtx.TickLowerBound())
+	}
+	return full
+}
+
+// peerTime is the time of the destination, as seen by the source.
+func (cchan *CommunicationChannel) peerTime() *Time {
+	return cchan.dstCtx.TickLowerBound()
 }
 
 func (cchan *CommunicationChannel) NextTime() (ret *Time) {
@@ -166,6 +273,12 @@ func (cchan *CommunicationChannel) NextTime() (ret *Time) {
 }
 
 func (cchan *CommunicationChannel) Enqueue(ce ChannelElement) (bool, *Time) {
+	if lookahead := cchan.sourceLookahead(); lookahead.Cmp(NewTime(0)) > 0 {
+		earliest := new(Time).Add(cchan.srcCtx.TickLowerBound(), lookahead)
+		if ce.Time.Cmp(earliest) < 0 {
+			panic(fmt.Sprintf("Element at time %v was sent on %s before %v, violating its lookahead of %v", &ce.Time, cchan.endpointString(), earliest, lookahead))
+		}
+	}
 	if cchan.IsFull() {
 		// currently full!
 		// In this case, consider one of the possibilities:
@@ -184,8 +297,33 @@ func (cchan *CommunicationChannel) Enqueue(ce ChannelElement) (bool, *Time) {
 		// check back again next cycle
 		return false, nil
 	}
+	cchan.sendInitialTokens()
 	cchan.incrSRDelta(1)
-	cchan.underlying <- ce
+	cchan.transmit(&ce)
+	if tagged, ok := cchan.srcCtx.(interface{ tagSet() *tagSet }); ok {
+		ce.Tags = tagged.tagSet().attach(ce.Tags)
+	}
+	if cchan.stats != nil || cchan.record != nil || cchan.tracer != nil {
+		srcTime := cchan.srcCtx.TickLowerBound()
+		if cchan.stats != nil {
+			cchan.stats.recordEnqueue(srcTime, &ce)
+		}
+		if cchan.record != nil {
+			cchan.record.recordEnqueue(srcTime, &ce)
+		}
+		if cchan.tracer != nil {
+			cchan.traceChannel(cchan.srcCtx, "enqueue", srcTime, &ce)
+		}
+	}
+	if cchan.trace != nil {
+		cchan.trace.recordEnqueue(&ce)
+	}
+	cchan.writes.Add(1)
+	cchan.sent++
+	cchan.underlying.send(ce)
+	if cchan.capacity == Rendezvous {
+		return true, cchan.awaitHandoff()
+	}
 	return true, nil
 }
 
@@ -198,77 +336,174 @@ var _ OutputChannel = (*CommunicationChannel)(nil)
 
 // NOT threadsafe -- we assume that peek/dequeue is only ever called from one thread.
 type InputChannel interface {
+	// If the status is Nothing, then nothing will arrive at or before the returned element's time.
 	Peek() (ChannelElement, Status)
 
 	// This is a nonblocking dequeue
 	Dequeue() (ChannelElement, Status)
+
+	// PeekN returns up to k elements from the front of the channel, which have arrived by the destination's
+	// current time, without taking them. Unlike Peek, elements which arrive in the future aren't included.
+	// The status is Ok if there were k of them, Closed if the channel is closed after them,
+	// and Nothing if the next one hasn't arrived yet.
+	PeekN(k int) ([]ChannelElement, Status)
+
+	// DequeueUntil takes every element which arrives by t, each stamped as with Dequeue.
+	// The status is Closed if the channel is closed after them, Nothing if none of them arrived,
+	// and Ok otherwise.
+	DequeueUntil(t *Time) ([]ChannelElement, Status)
 }
 
 func (cchan *CommunicationChannel) Peek() (ChannelElement, Status) {
-	if cchan.head != nil {
-		if cchan.headStatus != Nothing {
-			return *cchan.head, cchan.headStatus
-		} else {
-			// The headstatus is nothing
-			curTime := cchan.dstCtx.TickLowerBound()
-			if cchan.head.Time.Cmp(curTime) >= 0 {
-				// If the Nothing time is in the future, keep the nothing
-				return *cchan.head, Nothing
+	curTime := cchan.dstCtx.TickLowerBound()
+	ce, status := cchan.element(0, curTime)
+	if status == Nothing {
+		cchan.stats.recordEmpty(curTime)
+	}
+	return ce, status
+}
+
+// element returns the element at index i from the front of the channel, once it is known whether it arrives by until.
+// If it won't, the status is Nothing, and nothing else will arrive at or before the returned element's time.
+func (cchan *CommunicationChannel) element(i int, until *Time) (ChannelElement, Status) {
+	cchan.sendInitialTokens()
+	for {
+		writes := cchan.writes.Load()
+		if ce, ok, received := cchan.underlying.at(i); received {
+			if !ok {
+				return ce, Closed
 			}
-			// Nothing was in the past, continue ahead
+			ce.Time.Set(cchan.arrivalTime(&ce.Time))
+			return ce, Ok
 		}
-	}
-	// Otherwise, we need to pop a value off of the channel
-	select {
-	case v, ok := <-cchan.underlying:
-		cchan.head = &v
-		if !ok {
-			// Channel was closed
-			cchan.headStatus = Closed
-		} else {
-			cchan.headStatus = Ok
+		// there wasn't anything in the channel!
+		// Case 1: reader in future
+		// Case 2: reader in past/present
+		if cchan.quietUntil != nil && cchan.quietUntil.Cmp(until) >= 0 {
+			// We already know that nothing more arrives by then.
+			var ce ChannelElement
+			ce.Time.Set(cchan.quietUntil)
+			return ce, Nothing
 		}
-		return *cchan.head, cchan.headStatus
-	default:
+		cchan.awaitWrite(writes, until)
 	}
-	// there wasn't anything in the channel!
-	// Case 1: reader in future
-	// Case 2: reader in past/present
+}
 
-	curTime := cchan.dstCtx.TickLowerBound()
-	// Wait until the writer is in the past/present
-	srcTime := <-cchan.srcCtx.BlockUntil(curTime)
+// awaitWrite waits for the source to either send something more, or get far enough along that nothing more that it
+// sends can arrive by until, in which case quietUntil is updated.
+func (cchan *CommunicationChannel) awaitWrite(writes int64, until *Time) {
+	// Anything the writer sends from now on arrives at least a lookahead later, and always after the current tick.
+	lookahead := cchan.lookahead()
+	utils.Max[*Time](lookahead, OneTick, lookahead)
+	// Wait until the writer is far enough along that nothing more can arrive by the time we're after.
+	// Without any lookahead, that means the writer is in the past/present.
+	horizon := new(Time).Sub(until, lookahead)
+	horizon.Add(horizon, OneTick)
+	// An element arriving in the meantime also ends the wait, since it may be the one we're after.
+	rec := &waitRecord{ctx: cchan.dstCtx, target: cchan.srcCtx, until: horizon, channel: cchan, ready: func() bool {
+		return cchan.writes.Load() > writes || cchan.srcCtx.TickLowerBound().Cmp(horizon) >= 0
+	}}
+	srcTimeChan := cchan.srcCtx.BlockUntil(horizon)
+	var srcTime *Time
 	select {
-	case v, ok := <-cchan.underlying:
-		cchan.head = &v
-		if !ok {
-			// Channel was closed
-			cchan.headStatus = Closed
-		} else {
-			cchan.headStatus = Ok
-		}
-		return *cchan.head, cchan.headStatus
+	case <-cchan.underlying.arrived:
+		// The notification may have been for something which was already there, so the caller checks again.
+		return
+	case srcTime = <-srcTimeChan:
 	default:
-		// There wasn't anything in here, even after waiting.
-		cchan.head = &ChannelElement{}
-		cchan.headStatus = Nothing
-		cchan.head.Time.Set(srcTime)
-		return *cchan.head, cchan.headStatus
+		sim := simulationOf(cchan.dstCtx)
+		abort := sim.park(rec)
+		select {
+		case <-cchan.underlying.arrived:
+			sim.unpark(rec)
+			return
+		case srcTime = <-srcTimeChan:
+			sim.unpark(rec)
+		case <-abort:
+			sim.unpark(rec)
+			runtime.Goexit()
+		}
 	}
+	// Nothing can arrive until the writer's lookahead has passed.
+	quiet := new(Time).Add(srcTime, lookahead)
+	quiet.Sub(quiet, OneTick)
+	cchan.quietUntil = quiet
 }
 
 func (cchan *CommunicationChannel) Dequeue() (ce ChannelElement, status Status) {
 	ce, status = cchan.Peek()
-	if status != Nothing {
-		cchan.head = nil
-		// The earliest we could have dequeued the result is either when the packet arrived
-		// or the dequeuer's current time.
+	switch status {
+	case Ok:
+		cchan.take(&ce)
+	case Closed:
 		utils.Max[*Time](&ce.Time, cchan.dstCtx.TickLowerBound(), &ce.Time)
-		cchan.resp <- &ce.Time
 	}
 	return
 }
 
+// take removes ce, which arrived at ce.Time, from the front of the channel, and stamps it with when it was dequeued.
+func (cchan *CommunicationChannel) take(ce *ChannelElement) {
+	cchan.underlying.remove()
+	// The earliest we could have dequeued the result is either when the packet arrived
+	// or the dequeuer's current time.
+	utils.Max[*Time](&ce.Time, cchan.dstCtx.TickLowerBound(), &ce.Time)
+	if cchan.stats != nil {
+		cchan.stats.recordDequeue(ce)
+	}
+	if cchan.trace != nil {
+		cchan.trace.recordDequeue(ce)
+	}
+	if cchan.record != nil {
+		cchan.record.recordDequeue(ce)
+	}
+	if cchan.tracer != nil {
+		cchan.traceChannel(cchan.dstCtx, "dequeue", &ce.Time, ce)
+	}
+	// Only elements take up capacity, so there's nothing to acknowledge once the channel is closed.
+	cchan.taken.Add(1)
+	if cchan.capacity != Unbounded {
+		cchan.resp <- &ce.Time
+	}
+}
+
+func (cchan *CommunicationChannel) PeekN(k int) (result []ChannelElement, status Status) {
+	curTime := cchan.dstCtx.TickLowerBound()
+	for i := 0; i < k; i++ {
+		ce, status := cchan.element(i, curTime)
+		if status == Ok && ce.Time.Cmp(curTime) > 0 {
+			status = Nothing
+		}
+		if status != Ok {
+			if i == 0 && status == Nothing {
+				cchan.stats.recordEmpty(curTime)
+			}
+			return result, status
+		}
+		result = append(result, ce)
+	}
+	return result, Ok
+}
+
+func (cchan *CommunicationChannel) DequeueUntil(t *Time) (result []ChannelElement, status Status) {
+	for {
+		ce, status := cchan.element(0, t)
+		if status == Ok && ce.Time.Cmp(t) > 0 {
+			status = Nothing
+		}
+		switch {
+		case status == Ok:
+			cchan.take(&ce)
+			result = append(result, ce)
+		case status == Closed:
+			return result, Closed
+		case len(result) == 0:
+			return result, Nothing
+		default:
+			return result, Ok
+		}
+	}
+}
+
 func MakeChannelElement(time *Time, payload datatypes.DAMType) (ce ChannelElement) {
 	ce.Time.Set(time)
 	ce.Data = payload
@@ -277,11 +512,97 @@ func MakeChannelElement(time *Time, payload datatypes.DAMType) (ce ChannelElemen
 
 var _ InputChannel = (*CommunicationChannel)(nil)
 
-func MakeCommunicationChannel[T datatypes.DAMType](size int) *CommunicationChannel {
-	cchan := CommunicationChannel{
-		underlying: make(chan ChannelElement, size),
-		resp:       make(chan *Time, size),
+// Special sizes for MakeCommunicationChannel.
+const (
+	// A Rendezvous channel doesn't hold anything. The source waits in Enqueue until the destination takes the element,
+	// and can't send another until then.
+	Rendezvous = 0
+	// An Unbounded channel never fills up, so the source never waits for the destination.
+	Unbounded = -1
+)
+
+// MakeCommunicationChannel makes a channel which holds up to size elements of type T.
+// The size may also be Rendezvous or Unbounded.
+// Without any options, elements arrive at exactly the time they are stamped with.
+func MakeCommunicationChannel[T datatypes.DAMType](size int, options ...ChannelOption) *CommunicationChannel {
+	if size < 0 && size != Unbounded {
+		panic(fmt.Sprintf("Channels can't have a size of %d", size))
+	}
+	cchan := newCommunicationChannel(size, reflect.TypeOf((*T)(nil)).Elem().String())
+	for _, option := range options {
+		option(cchan)
+	}
+	return cchan
+}
+
+func newCommunicationChannel(size int, elemType string) *CommunicationChannel {
+	cchan := &CommunicationChannel{
+		underlying: makeMailbox(size),
 		capacity:   size,
+		elemType:   elemType,
+	}
+	// Unbounded channels are never full, so the destination doesn't need to say when it takes anything.
+	if size != Unbounded {
+		cchan.resp = make(chan *Time, cchan.slots())
+	}
+	return cchan
+}
+
+// slots is how many elements can be sent without hearing back from the destination.
+// Until a rendezvous is acknowledged, its element takes up a slot.
+func (cchan *CommunicationChannel) slots() int {
+	switch cchan.capacity {
+	case Unbounded:
+		return math.MaxInt
+	case Rendezvous:
+		return 1
+	}
+	return cchan.capacity
+}
+
+// awaitHandoff waits for the destination to take the element just sent on a rendezvous channel,
+// and returns when it did. If the destination finishes without taking it, the result is nil.
+func (cchan *CommunicationChannel) awaitHandoff() *Time {
+	finished := cchan.dstCtx.BlockUntil(InfiniteTime())
+	rec := &waitRecord{
+		ctx:     cchan.srcCtx,
+		target:  cchan.dstCtx,
+		channel: cchan,
+		// Unlike the length of resp, which goes down once we take the handoff time, this stays true once it's ready.
+		ready: func() bool {
+			return cchan.taken.Load() >= cchan.sent || cchan.dstCtx.TickLowerBound().IsInf()
+		},
+	}
+	var handoff *Time
+	select {
+	case handoff = <-cchan.resp:
+	case <-finished:
+	default:
+		sim := simulationOf(cchan.srcCtx)
+		abort := sim.park(rec)
+		select {
+		case handoff = <-cchan.resp:
+		case <-finished:
+		case <-abort:
+			sim.unpark(rec)
+			runtime.Goexit()
+		}
+		sim.unpark(rec)
+	}
+	if handoff == nil {
+		// The destination may have taken it on its way out.
+		select {
+		case handoff = <-cchan.resp:
+		default:
+			return nil
+		}
+	}
+	cchan.capacityMutex.Lock()
+	defer cchan.capacityMutex.Unlock()
+	if handoff.Cmp(cchan.srcCtx.TickLowerBound()) <= 0 {
+		cchan.sendRecvDelta--
+	} else {
+		cchan.nextTime = handoff
 	}
-	return &cchan
+	return new(Time).Set(handoff)
 }
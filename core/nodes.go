@@ -12,6 +12,66 @@ import (
 type LowLevelIO struct {
 	inputChannels  []*CommunicationChannel
 	outputChannels []*CommunicationChannel
+
+	// Named ports map to channel indices, or -1 if they haven't been connected yet.
+	inputPorts  map[string]int
+	outputPorts map[string]int
+}
+
+type hasLowLevelIO interface {
+	lowLevelIO() *LowLevelIO
+}
+
+func (llio *LowLevelIO) lowLevelIO() *LowLevelIO {
+	return llio
+}
+
+// In declares an input port with the given name, and returns it so that it can be connected with Graph.Connect.
+func (llio *LowLevelIO) In(name string) Port {
+	if llio.inputPorts == nil {
+		llio.inputPorts = map[string]int{}
+	}
+	if _, ok := llio.inputPorts[name]; !ok {
+		llio.inputPorts[name] = -1
+	}
+	return Port{io: llio, name: name}
+}
+
+// Out declares an output port with the given name, and returns it so that it can be connected with Graph.Connect.
+func (llio *LowLevelIO) Out(name string) Port {
+	if llio.outputPorts == nil {
+		llio.outputPorts = map[string]int{}
+	}
+	if _, ok := llio.outputPorts[name]; !ok {
+		llio.outputPorts[name] = -1
+	}
+	return Port{io: llio, name: name, output: true}
+}
+
+// InputID returns the index of the channel connected to the named input port.
+func (llio *LowLevelIO) InputID(name string) int {
+	id, ok := llio.inputPorts[name]
+	if !ok || id < 0 {
+		panic(fmt.Sprintf("Input port %q is not connected", name))
+	}
+	return id
+}
+
+// OutputID returns the index of the channel connected to the named output port.
+func (llio *LowLevelIO) OutputID(name string) int {
+	id, ok := llio.outputPorts[name]
+	if !ok || id < 0 {
+		panic(fmt.Sprintf("Output port %q is not connected", name))
+	}
+	return id
+}
+
+func (llio *LowLevelIO) NamedInputChannel(name string) InputChannel {
+	return llio.InputChannel(llio.InputID(name))
+}
+
+func (llio *LowLevelIO) NamedOutputChannel(name string) OutputChannel {
+	return llio.OutputChannel(llio.OutputID(name))
 }
 
 func (llio *LowLevelIO) AddInputChannel(channel *CommunicationChannel) (result int) {
@@ -40,10 +100,10 @@ func (llio *LowLevelIO) InitWithCtx(ctx ContextView) {
 
 func (llio *LowLevelIO) ConnectionString() string {
 	inputStrings := utils.Map(llio.inputChannels, func(chn *CommunicationChannel) string {
-		return fmt.Sprintf("%#v", chn.srcCtx)
+		return viewToString(chn.srcCtx)
 	})
 	outputStrings := utils.Map(llio.outputChannels, func(chn *CommunicationChannel) string {
-		return fmt.Sprintf("%#v", chn.dstCtx)
+		return viewToString(chn.dstCtx)
 	})
 	return fmt.Sprintf("LLIO{Inputs: %v, Outputs: %v}", strings.Join(inputStrings, ", "), strings.Join(outputStrings, ", "))
 }
@@ -54,6 +114,14 @@ func (llio *LowLevelIO) AddOutputChannel(channel *CommunicationChannel) (result
 	return
 }
 
+func (llio *LowLevelIO) NumInputChannels() int {
+	return len(llio.inputChannels)
+}
+
+func (llio *LowLevelIO) NumOutputChannels() int {
+	return len(llio.outputChannels)
+}
+
 func (prim *LowLevelIO) Cleanup() {
 	utils.Foreach(prim.outputChannels, func(c *CommunicationChannel) { c.CloseOutput() })
 }
@@ -70,6 +138,44 @@ type TickTime struct {
 	tickMutex sync.RWMutex
 
 	signalBuffer []signalElement
+
+	// Everything the node sends is stamped at least this many of its cycles past its current time.
+	lookahead Time
+
+	// The clock that the node's cycles are counted in
+	domain *ClockDomain
+
+	// The time the node finished at, once it has
+	finishedAt *Time
+
+	// What the time that the node advances is attributed to
+	activity       Activity
+	activityCycles [numActivities]Time
+
+	sim *simulation
+	// Only set if the simulation is being traced.
+	track *traceTrack
+}
+
+// SetLookahead declares the node's minimum latency, which readers use to skip ahead when nothing has been sent.
+// Like IncrCycles, it is counted in cycles of the node's clock domain. It should be set before the simulation starts.
+func (prim *TickTime) SetLookahead(lookahead *Time) {
+	prim.lookahead.Set(lookahead)
+}
+
+func (prim *TickTime) Lookahead() *Time {
+	if prim.domain != nil {
+		return prim.domain.CyclesToTime(&prim.lookahead)
+	}
+	return &prim.lookahead
+}
+
+func (prim *TickTime) bindSimulation(sim *simulation) {
+	prim.sim = sim
+}
+
+func (prim *TickTime) simulation() *simulation {
+	return prim.sim
 }
 
 func (prim *TickTime) scanAndWriteSignals() {
@@ -88,19 +194,34 @@ func (prim *TickTime) scanAndWriteSignals() {
 }
 
 func (prim *TickTime) IncrCycles(step *Time) {
+	prim.sim.exitIfAborted()
 	prim.tickMutex.Lock()
 	defer prim.tickMutex.Unlock()
+	previous := new(Time).Set(&prim.tickCount)
+	if prim.domain != nil {
+		step = prim.domain.CyclesToTime(step)
+	}
 	prim.tickCount.Add(&prim.tickCount, step)
+	prim.attribute(previous)
+	prim.track.advance(previous, &prim.tickCount, prim.activity)
 	prim.scanAndWriteSignals()
 }
 
 func (prim *TickTime) AdvanceToTime(newTime *Time) {
+	prim.sim.exitIfAborted()
 	prim.tickMutex.Lock()
 	defer prim.tickMutex.Unlock()
 	if newTime.Cmp(&prim.tickCount) < 0 {
 		return
 	}
+	previous := new(Time).Set(&prim.tickCount)
+	if prim.domain != nil {
+		// Nothing happens in between clock edges.
+		newTime = prim.domain.NextEdge(newTime)
+	}
 	prim.tickCount.Set(newTime)
+	prim.attribute(previous)
+	prim.track.advance(previous, &prim.tickCount, prim.activity)
 	prim.scanAndWriteSignals()
 }
 
@@ -144,16 +265,29 @@ func (prim *TickTime) BlockUntil(time *Time) <-chan *Time {
 }
 
 func (prim *TickTime) Cleanup() {
+	prim.tickMutex.Lock()
+	prim.finishedAt = new(Time).Set(&prim.tickCount)
+	prim.tickMutex.Unlock()
 	// This increments time to "done", and also notifies all listeners that the task is done.
 	prim.IncrCycles(InfiniteTime())
 }
 
+// finalTime returns how far the node got: the time it finished at, or its current time if it hasn't yet.
+func (prim *TickTime) finalTime() *Time {
+	prim.tickMutex.RLock()
+	defer prim.tickMutex.RUnlock()
+	if prim.finishedAt != nil {
+		return new(Time).Set(prim.finishedAt)
+	}
+	return new(Time).Set(&prim.tickCount)
+}
+
 type PrimitiveNode[T any] struct {
 	HasParent
 	id int
 
 	State *T
-	// TODO: Tags
+	tags  tagSet
 }
 
 type LLIOWithTime struct {
@@ -0,0 +1,50 @@
+// Package nodes is a library of common stream nodes, such as sources, sinks, and maps, built out of core.SimpleNode.
+//
+// Channels are numbered in the order they are added to a node. Nodes with a single input read from input 0,
+// and nodes with a single output write to output 0. Each node finishes once one of its inputs is closed,
+// which closes its outputs in turn.
+//
+// Latencies and initiation intervals (II) are in cycles of the node's clock. A node with an II of n accepts
+// a new input at most once every n cycles, and stamps each output latency cycles after the input it came from.
+// Nodes wait for room on their outputs before sending, so pipelines built out of them have backpressure.
+package nodes
+
+import (
+	"github.com/stanford-ppl/DAM/core"
+	"github.com/stanford-ppl/DAM/datatypes"
+	"github.com/stanford-ppl/DAM/utils"
+)
+
+// after returns the time that is the given number of the node's cycles after start.
+func after[T any](node *core.SimpleNode[T], start *core.Time, cycles int64) *core.Time {
+	return new(core.Time).Add(start, node.CyclesToTime(core.NewTime(cycles)))
+}
+
+// emit sends value on each of the outputs, at ready or once the outputs have room, whichever is later.
+func emit[T any](node *core.SimpleNode[T], ready *core.Time, value datatypes.DAMType, outputs ...int) {
+	core.AdvanceUntilCanEnqueue(node, outputs...)
+	at := new(core.Time).Set(ready)
+	utils.Max[*core.Time](at, node.TickLowerBound(), at)
+	for _, output := range outputs {
+		node.OutputChannel(output).Enqueue(core.MakeChannelElement(at, value))
+	}
+}
+
+// allInputs lists the indices of every input of node.
+func allInputs[T any](node *core.SimpleNode[T]) []int {
+	return indices(node.NumInputChannels())
+}
+
+// allOutputs lists the indices of every output of node.
+func allOutputs[T any](node *core.SimpleNode[T]) []int {
+	return indices(node.NumOutputChannels())
+}
+
+func indices(n int) []int {
+	return utils.Tabulate(make([]int, n), func(i int) int { return i })
+}
+
+// closed reports whether any of the inputs were closed.
+func closed(inputs []core.CEWithStatus) bool {
+	return utils.Exists(inputs, func(input core.CEWithStatus) bool { return input.Status == core.Closed })
+}
@@ -0,0 +1,170 @@
+package nodes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/core"
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+var fpt = datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+
+type producer interface {
+	AddOutputChannel(*core.CommunicationChannel) int
+}
+
+type consumer interface {
+	AddInputChannel(*core.CommunicationChannel) int
+}
+
+func connect(src producer, dst consumer, depth int) {
+	channel := core.MakeCommunicationChannel[datatypes.DAMType](depth)
+	src.AddOutputChannel(channel)
+	dst.AddInputChannel(channel)
+}
+
+func simulate(t *testing.T, nodes ...core.Context) core.SimulationResult {
+	ctx := core.MakePrimitiveContext(nil)
+	for _, node := range nodes {
+		ctx.AddChild(node)
+	}
+	result, err := core.Simulate(ctx, core.WithBackend(core.DeterministicBackend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func fixed(values ...int64) []datatypes.FixedPoint {
+	result := make([]datatypes.FixedPoint, len(values))
+	for i, value := range values {
+		result[i] = makeFixed(fpt, value)
+	}
+	return result
+}
+
+func ints(values []datatypes.FixedPoint) []int64 {
+	result := make([]int64, len(values))
+	for i, value := range values {
+		result[i] = value.ToInt().Int64()
+	}
+	return result
+}
+
+func times(sink *core.SimpleNode[SinkState]) []int64 {
+	result := make([]int64, len(sink.State.Elements))
+	for i, element := range sink.State.Elements {
+		time := element.Time.GetTime()
+		result[i] = time.Int64()
+	}
+	return result
+}
+
+func check[T any](t *testing.T, what string, expected, got T) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Expected %s to be %v, got %v", what, expected, got)
+	}
+}
+
+func TestSourceMapSink(t *testing.T) {
+	source := Source(fixed(0, 1, 2, 3), 2)
+	double := Map(func(x datatypes.FixedPoint) datatypes.FixedPoint { return datatypes.FixedAdd(x, x) }, 3, 1)
+	sink := Sink(1)
+	connect(source, double, 4)
+	connect(double, sink, 4)
+	simulate(t, source, double, sink)
+	check(t, "values", []int64{0, 2, 4, 6}, ints(Values[datatypes.FixedPoint](sink)))
+	check(t, "times", []int64{3, 5, 7, 9}, times(sink))
+}
+
+func TestCounterFilterReduce(t *testing.T) {
+	counter := Counter(fpt, MakeRange(0, 10), 1)
+	even := Filter(func(x datatypes.FixedPoint) bool { return x.ToInt().Int64()%2 == 0 }, 0, 1)
+	sum := Reduce(datatypes.FixedAdd, makeFixed(fpt, 0), 0, 2, 1)
+	sink := Sink(1)
+	connect(counter, even, 2)
+	connect(even, sum, 2)
+	connect(sum, sink, 2)
+	simulate(t, counter, even, sum, sink)
+	check(t, "values", []int64{0 + 2 + 4 + 6 + 8}, ints(Values[datatypes.FixedPoint](sink)))
+}
+
+func TestReduceGroups(t *testing.T) {
+	source := Source(fixed(1, 2, 3, 4, 5, 6), 0)
+	sum := Reduce(datatypes.FixedAdd, makeFixed(fpt, 0), 3, 2, 1)
+	sink := Sink(1)
+	connect(source, sum, 6)
+	connect(sum, sink, 2)
+	simulate(t, source, sum, sink)
+	check(t, "values", []int64{6, 15}, ints(Values[datatypes.FixedPoint](sink)))
+	// Each group is done 2 cycles after its last element.
+	check(t, "times", []int64{4, 7}, times(sink))
+}
+
+func TestCounterChain(t *testing.T) {
+	chain := CounterChain(fpt, 1, MakeRange(0, 2), Range{Start: 10, Stop: 16, Step: 2})
+	sink := Sink(1)
+	connect(chain, sink, 2)
+	simulate(t, chain, sink)
+	var got [][]int64
+	for _, vector := range Values[datatypes.Vector[datatypes.FixedPoint]](sink) {
+		got = append(got, []int64{vector.Get(0).ToInt().Int64(), vector.Get(1).ToInt().Int64()})
+	}
+	check(t, "indices", [][]int64{{0, 10}, {0, 12}, {0, 14}, {1, 10}, {1, 12}, {1, 14}}, got)
+}
+
+func TestBroadcastZipWith(t *testing.T) {
+	source := Source(fixed(1, 2, 3), 1)
+	broadcast := Broadcast(1)
+	square := ZipWith(func(inputs []datatypes.FixedPoint) datatypes.FixedPoint {
+		return datatypes.FixedMulFull(inputs[0], inputs[1]).FixedToFixed(fpt)
+	}, 1, 1)
+	sink := Sink(1)
+	connect(source, broadcast, 1)
+	connect(broadcast, square, 1)
+	connect(broadcast, square, 1)
+	connect(square, sink, 1)
+	simulate(t, source, broadcast, square, sink)
+	check(t, "values", []int64{1, 4, 9}, ints(Values[datatypes.FixedPoint](sink)))
+}
+
+func TestMergeRoundRobin(t *testing.T) {
+	a := Source(fixed(1, 2, 3), 0)
+	b := Source(fixed(10, 20, 30), 0)
+	merge := Merge(core.MakeRoundRobinPolicy(), 1)
+	sink := Sink(1)
+	connect(a, merge, 3)
+	connect(b, merge, 3)
+	connect(merge, sink, 6)
+	simulate(t, a, b, merge, sink)
+	check(t, "values", []int64{1, 10, 2, 20, 3, 30}, ints(Values[datatypes.FixedPoint](sink)))
+	check(t, "times", []int64{0, 1, 2, 3, 4, 5}, times(sink))
+}
+
+func TestRepeatAccumulateDelay(t *testing.T) {
+	source := Source(fixed(1, 2), 1)
+	repeat := Repeat(2, 1)
+	accumulate := Accumulate(datatypes.FixedAdd, makeFixed(fpt, 0), 0, 1)
+	delay := Delay(5)
+	sink := Sink(1)
+	connect(source, repeat, 4)
+	connect(repeat, accumulate, 4)
+	connect(accumulate, delay, 4)
+	connect(delay, sink, 4)
+	simulate(t, source, repeat, accumulate, delay, sink)
+	check(t, "values", []int64{1, 2, 4, 6}, ints(Values[datatypes.FixedPoint](sink)))
+	check(t, "times", []int64{5, 6, 7, 8}, times(sink))
+}
+
+func TestBackpressure(t *testing.T) {
+	source := Source(fixed(0, 1, 2, 3, 4, 5, 6, 7), 1)
+	sink := Sink(4)
+	connect(source, sink, 1)
+	result := simulate(t, source, sink)
+	// The sink takes an element every 4 cycles, and the source can only send once it has taken the previous one.
+	check(t, "times", []int64{0, 4, 8, 12, 16, 20, 24, 28}, times(sink))
+	finish := result.FinishTime(source).GetTime()
+	check(t, "source finish time", int64(25), finish.Int64())
+}
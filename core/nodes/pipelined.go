@@ -0,0 +1,142 @@
+package nodes
+
+import (
+	"fmt"
+
+	"github.com/stanford-ppl/DAM/core"
+	"github.com/stanford-ppl/DAM/datatypes"
+	"github.com/stanford-ppl/DAM/utils"
+)
+
+// PipelineState describes how full a pipelined node has been.
+type PipelineState struct {
+	// How many sets of inputs were taken in, and how many sets of outputs were sent
+	Accepted, Retired int
+	// The most sets of inputs that were in flight at once
+	MaxOccupancy int
+}
+
+type pipelineEntry struct {
+	ready   core.Time
+	outputs []datatypes.DAMType
+}
+
+// PipelinedNode models a pipelined unit computing fn. It takes one element from each of its inputs at most once every
+// ii cycles, and sends fn's results on its outputs, in order, latency cycles later.
+// Results that can't be sent because an output is full stay in the pipeline, which holds at most depth sets of
+// inputs at once. Once it is full, no more inputs are taken until something is sent, so a stall on an output
+// backs up into the inputs. A depth of 0 is just deep enough to keep up with ii.
+func PipelinedNode(fn func(inputs []datatypes.DAMType) []datatypes.DAMType, latency, ii int64, depth int) *core.SimpleNode[PipelineState] {
+	if ii <= 0 {
+		panic(fmt.Sprintf("A pipeline's initiation interval must be positive, got %d", ii))
+	}
+	if depth <= 0 {
+		depth = int((latency + ii - 1) / ii)
+		if depth < 1 {
+			depth = 1
+		}
+	}
+	return core.MakeSimpleNode(func(node *core.SimpleNode[PipelineState]) {
+		inputs, outputs := allInputs(node), allOutputs(node)
+		var inFlight []pipelineEntry
+		nextAccept := core.NewTime(0)
+		open := true
+		for open || len(inFlight) > 0 {
+			now := node.TickLowerBound()
+			// Send everything that's done, in order, for as long as the outputs have room.
+			blocked := false
+			for len(inFlight) > 0 && inFlight[0].ready.Cmp(now) <= 0 {
+				if blocked = utils.Exists(outputs, func(i int) bool { return node.OutputChannel(i).IsFull() }); blocked {
+					break
+				}
+				for i, output := range outputs {
+					node.OutputChannel(output).Enqueue(core.MakeChannelElement(now, inFlight[0].outputs[i]))
+				}
+				inFlight = inFlight[1:]
+				node.State.Retired++
+			}
+
+			next := core.InfiniteTime()
+			if open && len(inFlight) < depth {
+				// Take in the next set of inputs if they've all arrived, or else find out when they might.
+				arrival, status := peekAll(node, inputs)
+				switch {
+				case status == core.Closed:
+					open = false
+				case status == core.Ok && arrival.Cmp(now) <= 0 && nextAccept.Cmp(now) <= 0:
+					received := core.DequeueInputChansByID(node, inputs...)
+					entry := pipelineEntry{outputs: fn(utils.Map(received, func(ce core.CEWithStatus) datatypes.DAMType { return ce.Data }))}
+					if len(entry.outputs) != len(outputs) {
+						panic(fmt.Sprintf("Pipeline function returned %d results for %d outputs", len(entry.outputs), len(outputs)))
+					}
+					entry.ready.Set(after(node, now, latency))
+					inFlight = append(inFlight, entry)
+					node.State.Accepted++
+					if len(inFlight) > node.State.MaxOccupancy {
+						node.State.MaxOccupancy = len(inFlight)
+					}
+					nextAccept = after(node, now, ii)
+					// Anything with no latency can be sent straight away.
+					continue
+				default:
+					utils.Max[*core.Time](arrival, nextAccept, arrival)
+					next = arrival
+				}
+			}
+			if len(inFlight) > 0 {
+				if !blocked {
+					utils.Min[*core.Time](&inFlight[0].ready, next, next)
+				} else {
+					// Try again once an output has room.
+					utils.Min[*core.Time](freedAt(node, outputs), next, next)
+				}
+			}
+			if next.IsInf() {
+				continue
+			}
+			activity := core.Compute
+			switch {
+			case blocked:
+				activity = core.Stalled
+			case len(inFlight) == 0:
+				activity = core.Starved
+			}
+			previous := node.SetActivity(activity)
+			node.AdvanceToTime(next)
+			node.SetActivity(previous)
+		}
+	}, new(PipelineState))
+}
+
+// peekAll returns when every input will have an element, or Closed if any of them is closed.
+// If any of the inputs is still empty, the status is Nothing, and the time is only a lower bound.
+func peekAll[T any](node *core.SimpleNode[T], inputs []int) (*core.Time, core.Status) {
+	arrival, result := core.NewTime(0), core.Ok
+	for _, i := range inputs {
+		ce, status := node.InputChannel(i).Peek()
+		switch status {
+		case core.Closed:
+			return nil, core.Closed
+		case core.Nothing:
+			// Nothing can arrive until after ce.Time.
+			ce.Time.Add(&ce.Time, core.OneTick)
+			result = core.Nothing
+		}
+		utils.Max[*core.Time](arrival, &ce.Time, arrival)
+	}
+	return arrival, result
+}
+
+// freedAt returns the earliest that one of the full outputs might have room.
+func freedAt[T any](node *core.SimpleNode[T], outputs []int) *core.Time {
+	result := core.InfiniteTime()
+	for _, i := range outputs {
+		if nextTime := node.OutputChannel(i).NextTime(); nextTime != nil {
+			utils.Min[*core.Time](nextTime, result, result)
+		} else {
+			// The destination hasn't taken anything yet, so check again next cycle.
+			utils.Min[*core.Time](after(node, node.TickLowerBound(), 1), result, result)
+		}
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func double(inputs []datatypes.DAMType) []datatypes.DAMType {
+	x := inputs[0].(datatypes.FixedPoint)
+	return []datatypes.DAMType{datatypes.FixedAdd(x, x)}
+}
+
+func TestPipelinedThroughput(t *testing.T) {
+	for _, ii := range []int64{1, 2} {
+		source := Source(fixed(0, 1, 2, 3, 4, 5, 6, 7), 0)
+		pipeline := PipelinedNode(double, 4, ii, 0)
+		sink := Sink(1)
+		connect(source, pipeline, 8)
+		connect(pipeline, sink, 8)
+		simulate(t, source, pipeline, sink)
+		check(t, "values", []int64{0, 2, 4, 6, 8, 10, 12, 14}, ints(Values[datatypes.FixedPoint](sink)))
+		expected := make([]int64, 8)
+		for i := range expected {
+			expected[i] = 4 + int64(i)*ii
+		}
+		check(t, "times", expected, times(sink))
+		check(t, "state", PipelineState{Accepted: 8, Retired: 8, MaxOccupancy: int((4 + ii - 1) / ii)}, *pipeline.State)
+	}
+}
+
+func TestPipelinedDepth(t *testing.T) {
+	// With only two stages, a new input can't be taken until the oldest one is done.
+	source := Source(fixed(0, 1, 2, 3), 0)
+	pipeline := PipelinedNode(double, 4, 1, 2)
+	sink := Sink(1)
+	connect(source, pipeline, 4)
+	connect(pipeline, sink, 4)
+	simulate(t, source, pipeline, sink)
+	check(t, "times", []int64{4, 5, 8, 9}, times(sink))
+	check(t, "max occupancy", 2, pipeline.State.MaxOccupancy)
+}
+
+func TestPipelinedBackpressure(t *testing.T) {
+	source := Source(fixed(0, 1, 2, 3, 4, 5), 0)
+	pipeline := PipelinedNode(double, 2, 1, 0)
+	sink := Sink(4)
+	connect(source, pipeline, 6)
+	connect(pipeline, sink, 1)
+	result := simulate(t, source, pipeline, sink)
+	check(t, "values", []int64{0, 2, 4, 6, 8, 10}, ints(Values[datatypes.FixedPoint](sink)))
+	// The sink only takes an element every 4 cycles, so results pile up in the pipeline.
+	check(t, "times", []int64{2, 6, 10, 14, 18, 22}, times(sink))
+	check(t, "max occupancy", 2, pipeline.State.MaxOccupancy)
+	finish := result.FinishTime(pipeline).GetTime()
+	check(t, "pipeline finish time", int64(19), finish.Int64())
+}
+
+func TestPipelinedMultipleOutputs(t *testing.T) {
+	a := Source(fixed(5, 6, 7), 1)
+	b := Source(fixed(1, 2, 3), 0)
+	sumProduct := PipelinedNode(func(inputs []datatypes.DAMType) []datatypes.DAMType {
+		x, y := inputs[0].(datatypes.FixedPoint), inputs[1].(datatypes.FixedPoint)
+		return []datatypes.DAMType{datatypes.FixedAdd(x, y), datatypes.FixedMulFull(x, y).FixedToFixed(fpt)}
+	}, 3, 1, 0)
+	sums, products := Sink(1), Sink(1)
+	connect(a, sumProduct, 3)
+	connect(b, sumProduct, 3)
+	connect(sumProduct, sums, 3)
+	connect(sumProduct, products, 3)
+	simulate(t, a, b, sumProduct, sums, products)
+	check(t, "sums", []int64{6, 8, 10}, ints(Values[datatypes.FixedPoint](sums)))
+	check(t, "products", []int64{5, 12, 21}, ints(Values[datatypes.FixedPoint](products)))
+	// Input a only sends one element a cycle, and b waits for it.
+	check(t, "times", []int64{3, 4, 5}, times(sums))
+	check(t, "times", times(sums), times(products))
+}
@@ -0,0 +1,39 @@
+package nodes
+
+import "github.com/stanford-ppl/DAM/core"
+
+// Broadcast sends each element from input 0 on every output, once they all have room.
+func Broadcast(ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		outputs := allOutputs(node)
+		for {
+			input := core.DequeueInputChansByID(node, 0)[0]
+			if input.Status == core.Closed {
+				return
+			}
+			start := node.TickLowerBound()
+			emit(node, start, input.Data, outputs...)
+			node.AdvanceToTime(after(node, start, ii))
+		}
+	}, (*any)(nil))
+}
+
+// Merge sends the elements from all of its inputs on output 0, picking between inputs that are ready at the same
+// time with policy. It finishes once every input is closed.
+func Merge(policy core.SelectPolicy, ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		bundles := make([][]int, node.NumInputChannels())
+		for i := range bundles {
+			bundles[i] = []int{i}
+		}
+		for {
+			winner, inputs := core.Select(node, policy, bundles...)
+			if winner < 0 {
+				return
+			}
+			start := node.TickLowerBound()
+			emit(node, start, inputs[0].Data, 0)
+			node.AdvanceToTime(after(node, start, ii))
+		}
+	}, (*any)(nil))
+}
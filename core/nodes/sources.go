@@ -0,0 +1,120 @@
+package nodes
+
+import (
+	"fmt"
+
+	"github.com/stanford-ppl/DAM/core"
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// SourceFunc sends gen(0), gen(1), ... on output 0, one every ii cycles, until gen returns false.
+func SourceFunc[T datatypes.DAMType](gen func(i int) (T, bool), ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		for i := 0; ; i++ {
+			value, ok := gen(i)
+			if !ok {
+				return
+			}
+			start := node.TickLowerBound()
+			emit(node, start, value, 0)
+			node.AdvanceToTime(after(node, start, ii))
+		}
+	}, (*any)(nil))
+}
+
+// Source sends each of the values on output 0, one every ii cycles.
+func Source[T datatypes.DAMType](values []T, ii int64) *core.SimpleNode[any] {
+	return SourceFunc(func(i int) (value T, ok bool) {
+		if i >= len(values) {
+			return
+		}
+		return values[i], true
+	}, ii)
+}
+
+// A Range counts from Start up to, but not including, Stop.
+type Range struct {
+	Start, Stop, Step int64
+}
+
+// MakeRange counts from start to stop in steps of 1.
+func MakeRange(start, stop int64) Range {
+	return Range{Start: start, Stop: stop, Step: 1}
+}
+
+func (r Range) len() int64 {
+	if r.Step <= 0 {
+		panic(fmt.Sprintf("Range %v must have a positive step", r))
+	}
+	if r.Stop <= r.Start {
+		return 0
+	}
+	return (r.Stop - r.Start + r.Step - 1) / r.Step
+}
+
+func makeFixed(tp datatypes.FixedPointType, value int64) datatypes.FixedPoint {
+	result := datatypes.FixedPoint{Tp: tp}
+	result.SetInt64(value)
+	return result
+}
+
+// Counter sends each value in r on output 0 as a FixedPoint of type tp, one every ii cycles.
+func Counter(tp datatypes.FixedPointType, r Range, ii int64) *core.SimpleNode[any] {
+	count := r.len()
+	return SourceFunc(func(i int) (value datatypes.FixedPoint, ok bool) {
+		if int64(i) >= count {
+			return
+		}
+		return makeFixed(tp, r.Start+int64(i)*r.Step), true
+	}, ii)
+}
+
+// CounterChain runs nested counters, sending a vector with one index for each range on output 0 every ii cycles.
+// The last range is the innermost, so it counts the fastest.
+func CounterChain(tp datatypes.FixedPointType, ii int64, ranges ...Range) *core.SimpleNode[any] {
+	total := int64(1)
+	for _, r := range ranges {
+		total *= r.len()
+	}
+	return SourceFunc(func(i int) (value datatypes.Vector[datatypes.FixedPoint], ok bool) {
+		if int64(i) >= total {
+			return
+		}
+		value = datatypes.NewVector[datatypes.FixedPoint](len(ranges))
+		remaining := int64(i)
+		for lane := len(ranges) - 1; lane >= 0; lane-- {
+			r := ranges[lane]
+			value.Set(lane, makeFixed(tp, r.Start+remaining%r.len()*r.Step))
+			remaining /= r.len()
+		}
+		return value, true
+	}, ii)
+}
+
+// SinkState holds everything a sink has received.
+type SinkState struct {
+	Elements []core.ChannelElement
+}
+
+// Sink receives everything from input 0, one element every ii cycles, until it is closed.
+func Sink(ii int64) *core.SimpleNode[SinkState] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[SinkState]) {
+		for {
+			input := core.DequeueInputChansByID(node, 0)[0]
+			if input.Status == core.Closed {
+				return
+			}
+			node.State.Elements = append(node.State.Elements, input.ChannelElement)
+			node.AdvanceToTime(after(node, node.TickLowerBound(), ii))
+		}
+	}, new(SinkState))
+}
+
+// Values returns the data of every element received by a sink, which must all be of type T.
+func Values[T datatypes.DAMType](sink *core.SimpleNode[SinkState]) []T {
+	result := make([]T, len(sink.State.Elements))
+	for i, element := range sink.State.Elements {
+		result[i] = element.Data.(T)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package nodes
+
+import (
+	"github.com/stanford-ppl/DAM/core"
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// ZipWith takes one element from each input, and sends fn of them on output 0.
+func ZipWith[In, Out datatypes.DAMType](fn func(inputs []In) Out, latency, ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		channels := allInputs(node)
+		for {
+			inputs := core.DequeueInputChansByID(node, channels...)
+			if closed(inputs) {
+				return
+			}
+			values := make([]In, len(inputs))
+			for i, input := range inputs {
+				values[i] = input.Data.(In)
+			}
+			start := node.TickLowerBound()
+			emit(node, after(node, start, latency), fn(values), 0)
+			node.AdvanceToTime(after(node, start, ii))
+		}
+	}, (*any)(nil))
+}
+
+// Map sends fn of each element from input 0 on output 0.
+func Map[In, Out datatypes.DAMType](fn func(In) Out, latency, ii int64) *core.SimpleNode[any] {
+	return ZipWith(func(inputs []In) Out { return fn(inputs[0]) }, latency, ii)
+}
+
+// Delay passes elements from input 0 to output 0, latency cycles later. It accepts an element every cycle.
+func Delay(latency int64) *core.SimpleNode[any] {
+	return Map(func(value datatypes.DAMType) datatypes.DAMType { return value }, latency, 1)
+}
+
+// Filter passes on the elements from input 0 for which keep is true, and drops the rest.
+func Filter[T datatypes.DAMType](keep func(T) bool, latency, ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		for {
+			input := core.DequeueInputChansByID(node, 0)[0]
+			if input.Status == core.Closed {
+				return
+			}
+			start := node.TickLowerBound()
+			if value := input.Data.(T); keep(value) {
+				emit(node, after(node, start, latency), value, 0)
+			}
+			node.AdvanceToTime(after(node, start, ii))
+		}
+	}, (*any)(nil))
+}
+
+// Repeat sends each element from input 0 on output 0 times times, one every ii cycles.
+func Repeat(times int, ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		for {
+			input := core.DequeueInputChansByID(node, 0)[0]
+			if input.Status == core.Closed {
+				return
+			}
+			for i := 0; i < times; i++ {
+				start := node.TickLowerBound()
+				emit(node, start, input.Data, 0)
+				node.AdvanceToTime(after(node, start, ii))
+			}
+		}
+	}, (*any)(nil))
+}
+
+// fold combines the elements from input 0 with fn, starting from init. If every is positive, the result is sent
+// and reset after every that many elements, and otherwise it is sent once the input is closed.
+// If running is set, the result is sent after every element instead, and never reset.
+func fold[T datatypes.DAMType](fn func(acc, value T) T, init T, every int, running bool, latency, ii int64) *core.SimpleNode[any] {
+	return core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		acc, count := init, 0
+		for {
+			input := core.DequeueInputChansByID(node, 0)[0]
+			if input.Status == core.Closed {
+				if !running && every <= 0 {
+					emit(node, after(node, node.TickLowerBound(), latency), acc, 0)
+				}
+				return
+			}
+			start := node.TickLowerBound()
+			acc = fn(acc, input.Data.(T))
+			count++
+			switch {
+			case running:
+				emit(node, after(node, start, latency), acc, 0)
+			case every > 0 && count == every:
+				emit(node, after(node, start, latency), acc, 0)
+				acc, count = init, 0
+			}
+			node.AdvanceToTime(after(node, start, ii))
+		}
+	}, (*any)(nil))
+}
+
+// Reduce folds each group of every elements from input 0 into a single result with fn, starting from init,
+// and sends it on output 0. If every isn't positive, the whole stream is reduced, and the result is sent once
+// input 0 is closed.
+func Reduce[T datatypes.DAMType](fn func(acc, value T) T, init T, every int, latency, ii int64) *core.SimpleNode[any] {
+	return fold(fn, init, every, false, latency, ii)
+}
+
+// Accumulate folds every element from input 0 into a running result with fn, starting from init,
+// and sends the result so far on output 0 after each one.
+func Accumulate[T datatypes.DAMType](fn func(acc, value T) T, init T, latency, ii int64) *core.SimpleNode[any] {
+	return fold(fn, init, 0, true, latency, ii)
+}
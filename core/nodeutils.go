@@ -1,10 +1,6 @@
 package core
 
-import (
-	"fmt"
-
-	"github.com/stanford-ppl/DAM/utils"
-)
+import "fmt"
 
 type CEWithStatus struct {
 	ChannelElement
@@ -18,23 +14,29 @@ type DeqInputChans interface {
 	TickLowerBound() *Time
 }
 
+// awaitInput advances node until cc has an element or is closed.
+// Peek only returns once the writer has moved on or something has arrived, so rather than stepping a tick at a time,
+// node jumps straight past the time that Peek guarantees is empty.
+func awaitInput(node DeqInputChans, cc InputChannel) {
+	for {
+		cE, status := cc.Peek()
+		node.AdvanceToTime(&cE.Time)
+		if status != Nothing {
+			return
+		}
+		node.IncrCycles(OneTick)
+	}
+}
+
 func DequeueInputChansByID(node DeqInputChans, channelIndices ...int) (ret []CEWithStatus) {
 	ret = make([]CEWithStatus, len(channelIndices))
+	restore := during(node, Starved)
 	for _, i := range channelIndices {
-		cc := node.InputChannel(i)
-	L:
-		for {
-			cE, status := cc.Peek()
-			node.AdvanceToTime(&cE.Time)
-			switch status {
-			case Nothing:
-				node.IncrCycles(OneTick)
-			default:
-				break L
-			}
-		}
+		awaitInput(node, node.InputChannel(i))
 	}
 
+	restore()
+
 	// At this point, we haven't actually done any work, but we've advanced to the point where all elements are visible.
 	for sub, i := range channelIndices {
 		cc := node.InputChannel(i)
@@ -49,20 +51,13 @@ func DequeueInputChansByID(node DeqInputChans, channelIndices ...int) (ret []CEW
 
 func DequeueInputChannels(node DeqInputChans, chans ...InputChannel) (ret []CEWithStatus) {
 	ret = make([]CEWithStatus, len(chans))
+	restore := during(node, Starved)
 	for _, cc := range chans {
-	L:
-		for {
-			cE, status := cc.Peek()
-			node.AdvanceToTime(&cE.Time)
-			switch status {
-			case Nothing:
-				node.IncrCycles(OneTick)
-			default:
-				break L
-			}
-		}
+		awaitInput(node, cc)
 	}
 
+	restore()
+
 	// At this point, we haven't actually done any work, but we've advanced to the point where all elements are visible.
 	for i, cc := range chans {
 		cE, status := cc.Dequeue()
@@ -75,71 +70,43 @@ func DequeueInputChannels(node DeqInputChans, chans ...InputChannel) (ret []CEWi
 }
 
 // Advances the node to time when at least one bundle is available, and dequeues from it.
+// Ties go to the lowest-indexed bundle; use Select for other policies.
 // If all of the channels are closed, then we return (-1, nil)
 func DequeueInputBundles(node DeqInputChans, channelBundles ...[]int) (int, []CEWithStatus) {
-	for {
-		curTime := node.TickLowerBound()
-		nextTime := InfiniteTime()
-		for i, bundle := range channelBundles {
-			bundleNextTime := NewTime(0)
-			var ready bool = true
-		L:
-			for _, chanInd := range bundle {
-				cc := node.InputChannel(chanInd)
-				cE, status := cc.Peek()
-				switch status {
-				case Nothing:
-					tmp := Time{}
-					tmp.Add(&cE.Time, OneTick)
-					utils.Max[*Time](bundleNextTime, &tmp, bundleNextTime)
-					ready = false
-				case Closed:
-					// If the channel is closed, then there's no more data coming through.
-					ready = false
-					bundleNextTime = InfiniteTime()
-					break L
-				default:
-					if cE.Time.Cmp(curTime) > 0 {
-						ready = false
-					}
-					utils.Max[*Time](bundleNextTime, &cE.Time, bundleNextTime)
-				}
-			}
-			if ready {
-				return i, DequeueInputChansByID(node, bundle...)
-			} else {
-				utils.Min[*Time](nextTime, bundleNextTime, nextTime)
-			}
-		}
-		if nextTime.IsInf() {
-			return -1, nil
-		}
-		// Otherwise, advance to nextTime, try again
-		node.AdvanceToTime(nextTime)
-	}
+	return Select(node, FixedPriorityPolicy{}, channelBundles...)
 }
 
 type EnqOutputChans interface {
 	OutputChannel(int) OutputChannel
 	AdvanceToTime(*Time)
 	IncrCycles(*Time)
+	TickLowerBound() *Time
 }
 
 func AdvanceUntilCanEnqueue(node EnqOutputChans, chanIndices ...int) {
+	defer during(node, Stalled)()
 	for _, i := range chanIndices {
 		cc := node.OutputChannel(i)
 		for {
-			if cc.IsFull() {
-				nextTime := cc.NextTime()
-				if nextTime != nil {
-					node.AdvanceToTime(nextTime)
-					break
-				} else {
-					node.IncrCycles(OneTick)
-				}
-			} else {
+			// Read before IsFull, which catches up on everything the destination had dequeued by then.
+			var peer *Time
+			if withPeer, ok := cc.(interface{ peerTime() *Time }); ok {
+				peer = withPeer.peerTime()
+			}
+			if !cc.IsFull() {
+				break
+			}
+			nextTime := cc.NextTime()
+			if nextTime != nil {
+				node.AdvanceToTime(nextTime)
 				break
 			}
+			// Nothing had been dequeued by the time the destination was at, so the channel stays full until then.
+			if now := node.TickLowerBound(); peer != nil && !peer.IsInf() && peer.Cmp(now) > 0 {
+				node.AdvanceToTime(peer)
+			} else {
+				node.IncrCycles(OneTick)
+			}
 		}
 	}
 }
@@ -153,12 +120,17 @@ func CtxToString(ctx Context) string {
 	if ctx.ParentContext() != nil {
 		parentString = CtxToString(ctx.ParentContext()) + "."
 	}
+	return parentString + ctxName(ctx)
+}
+
+// ctxName names ctx on its own, without the names of its parents.
+func ctxName(ctx Context) string {
 	switch context := ctx.(type) {
 	case fmt.Stringer:
-		return fmt.Sprintf("%s%s", parentString, context.String())
+		return context.String()
 	case HasID:
-		return fmt.Sprintf("%s%T(id=%d)", parentString, ctx, context.GetID())
+		return fmt.Sprintf("%T(id=%d)", ctx, context.GetID())
 	default:
-		return fmt.Sprintf("%s%T(%p)", parentString, ctx, &ctx)
+		return fmt.Sprintf("%T(%p)", ctx, ctx)
 	}
 }
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// The consumer is idle for longer than the producer could ever step through one tick at a time.
+func TestAdvanceUntilCanEnqueueJumpsToDequeue(t *testing.T) {
+	const idle = int64(1) << 40
+	for _, backend := range backends {
+		channel := MakeCommunicationChannel[datatypes.Bit](1)
+		var unblockedAt *Time
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			for i := 0; i < 2; i++ {
+				AdvanceUntilCanEnqueue(node, 0)
+				node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			}
+			unblockedAt = node.TickLowerBound()
+		}, (*any)(nil))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.IncrCycles(NewTime(idle))
+			DequeueInputChansByID(node, 0)
+			node.IncrCycles(NewTime(7))
+			DequeueInputChansByID(node, 0)
+		}, (*any)(nil))
+		runPair(t, backend, channel, producer, consumer)
+		// The second element can only go in once the first was dequeued.
+		// In parallel, the producer may see the consumer reach idle just before it dequeues, and get in a tick later.
+		latest := NewTime(idle)
+		if backend == ParallelBackend {
+			latest.Add(latest, OneTick)
+		}
+		if unblockedAt.Cmp(NewTime(idle)) < 0 || unblockedAt.Cmp(latest) > 0 {
+			t.Errorf("%v: expected the producer to be unblocked at %d, got %v", backend, idle, unblockedAt)
+		}
+	}
+}
+
+// The producer waits on the consumer after sending, so the consumer has to notice the element without the
+// producer's time moving.
+func TestDequeueWakesOnArrival(t *testing.T) {
+	for _, backend := range backends {
+		channel := MakeCommunicationChannel[datatypes.Bit](1)
+		var received ChannelElement
+		var consumer *SimpleNode[any]
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(NewTime(3), datatypes.Bit{}))
+			WaitUntil(node, consumer, NewTime(100))
+		}, (*any)(nil))
+		consumer = MakeSimpleNode(func(node *SimpleNode[any]) {
+			received = DequeueInputChansByID(node, 0)[0].ChannelElement
+			node.IncrCycles(NewTime(100))
+		}, (*any)(nil))
+		runPair(t, backend, channel, producer, consumer)
+		if received.Time.Cmp(NewTime(3)) != 0 {
+			t.Errorf("%v: expected the element at time 3, got %v", backend, &received.Time)
+		}
+	}
+}
+
+func TestDequeueAfterClose(t *testing.T) {
+	for _, backend := range backends {
+		channel := MakeCommunicationChannel[datatypes.Bit](1)
+		var statuses []Status
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(NewTime(3), datatypes.Bit{}))
+		}, (*any)(nil))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			// Dequeueing from a closed channel takes nothing out of it, so it doesn't hand any capacity back to
+			// the producer, and can be done any number of times without filling up the acknowledgements.
+			for len(statuses) < 5 {
+				if input := DequeueInputChansByID(node, 0)[0]; input.Status != Nothing {
+					statuses = append(statuses, input.Status)
+				}
+				node.IncrCycles(OneTick)
+			}
+		}, (*any)(nil))
+		runPair(t, backend, channel, producer, consumer)
+		expected := []Status{Ok, Closed, Closed, Closed, Closed}
+		if !reflect.DeepEqual(statuses, expected) {
+			t.Errorf("%v: expected %v, got %v", backend, expected, statuses)
+		}
+		if len(channel.resp) > 1 {
+			t.Errorf("%v: expected only the element to be acknowledged, got %d acknowledgements", backend, len(channel.resp))
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// An OutputLog merges the lines printed by every context into a single stream, ordered by the simulated time
+// they were printed at rather than by when their goroutines happened to run.
+// Ties go to the context that comes first in the graph, and then to the order they were printed in.
+// Lines are written once every context has moved past their time, so that nothing can come before them.
+type OutputLog struct {
+	w io.Writer
+
+	mutex   sync.Mutex
+	root    Context
+	order   map[Context]int
+	names   map[Context]string
+	pending outputHeap
+	printed int
+	err     error
+}
+
+func MakeOutputLog(w io.Writer) *OutputLog {
+	return &OutputLog{w: w}
+}
+
+// WithOutputLog sends everything printed with Printf and Println to log.
+// Lines are written out as the simulation progresses, once every context has moved past them, and the rest
+// when it finishes.
+func WithOutputLog(log *OutputLog) SimulationOption {
+	return func(conf *simulationConfig) {
+		conf.outputLog = log
+	}
+}
+
+type outputLine struct {
+	time    Time
+	context int
+	seq     int
+	text    string
+}
+
+type outputHeap []*outputLine
+
+func (h outputHeap) Len() int { return len(h) }
+func (h outputHeap) Less(i, j int) bool {
+	if cmp := h[i].time.Cmp(&h[j].time); cmp != 0 {
+		return cmp < 0
+	}
+	if h[i].context != h[j].context {
+		return h[i].context < h[j].context
+	}
+	return h[i].seq < h[j].seq
+}
+func (h outputHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *outputHeap) Push(x any)   { *h = append(*h, x.(*outputLine)) }
+func (h *outputHeap) Pop() any {
+	old := *h
+	line := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return line
+}
+
+// start numbers the contexts under root, which break ties between lines printed at the same time.
+// It also names them by where they are under root, since the default name of a context can include its address,
+// which would keep the log from being compared between runs.
+func (log *OutputLog) start(root Context) {
+	if log == nil {
+		return
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.root = root
+	log.order = map[Context]int{}
+	walkContexts(root, func(ctx Context) {
+		log.order[ctx] = len(log.order)
+	})
+	log.names = map[Context]string{}
+	log.nameContexts(root, "", 0)
+}
+
+// nameContexts names ctx, the index'th child of the context named prefix, and everything under it.
+// Contexts without a name of their own are named after their type and index instead.
+func (log *OutputLog) nameContexts(ctx Context, prefix string, index int) {
+	name := ctxName(ctx)
+	switch ctx.(type) {
+	case fmt.Stringer, HasID:
+	default:
+		name = fmt.Sprintf("%T[%d]", ctx, index)
+	}
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+	log.names[ctx] = name
+	if parent, ok := ctx.(hasChildren); ok {
+		for i, child := range parent.Children() {
+			log.nameContexts(child, name, i)
+		}
+	}
+}
+
+// name returns what ctx is called in the log.
+func (log *OutputLog) name(ctx Context) string {
+	if name, ok := log.names[ctx]; ok {
+		return name
+	}
+	return CtxToString(ctx)
+}
+
+func (log *OutputLog) print(ctx Context, text string) {
+	line := &outputLine{text: strings.TrimSuffix(text, "\n")}
+	line.time.Set(ctx.TickLowerBound())
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	order, ok := log.order[ctx]
+	if !ok {
+		// Contexts added after the simulation started go last.
+		order = len(log.order)
+	}
+	line.context, line.seq = order, log.printed
+	log.printed++
+	line.text = fmt.Sprintf("[%v] %s: %s", &line.time, log.name(ctx), line.text)
+	heap.Push(&log.pending, line)
+	log.flushLocked(log.root.TickLowerBound())
+}
+
+// flushLocked writes every line from before horizon, since no context can print anything earlier.
+func (log *OutputLog) flushLocked(horizon *Time) {
+	for len(log.pending) > 0 && (horizon == nil || log.pending[0].time.Cmp(horizon) < 0) {
+		line := heap.Pop(&log.pending).(*outputLine)
+		if log.err == nil {
+			_, log.err = fmt.Fprintln(log.w, line.text)
+		}
+	}
+}
+
+// flush writes the lines that every context has moved past since they were printed.
+func (log *OutputLog) flush() {
+	if log == nil {
+		return
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if len(log.pending) > 0 {
+		log.flushLocked(log.root.TickLowerBound())
+	}
+}
+
+// finish writes whatever is left once the simulation is over.
+func (log *OutputLog) finish() {
+	if log == nil {
+		return
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.flushLocked(nil)
+}
+
+// Err returns the first error from writing out the log.
+func (log *OutputLog) Err() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.err
+}
+
+// Printf prints a line from ctx, stamped with its current time, to the OutputLog of its simulation.
+// Without an OutputLog, the line is printed to stdout straight away.
+func Printf(ctx Context, format string, args ...any) {
+	if log := simulationOf(ctx).outputLog(); log != nil {
+		log.print(ctx, fmt.Sprintf(format, args...))
+		return
+	}
+	fmt.Fprintf(os.Stdout, format, args...)
+}
+
+// Println is like Printf, but formats args like fmt.Println.
+func Println(ctx Context, args ...any) {
+	if log := simulationOf(ctx).outputLog(); log != nil {
+		log.print(ctx, fmt.Sprintln(args...))
+		return
+	}
+	fmt.Fprintln(os.Stdout, args...)
+}
+
+func (sim *simulation) outputLog() *OutputLog {
+	if sim == nil {
+		return nil
+	}
+	return sim.output
+}
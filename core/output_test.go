@@ -0,0 +1,149 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputLogOrdersByTime(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	// Each node prints at the times it's given, so the lines interleave.
+	makePrinter := func(times ...int64) *SimpleNode[any] {
+		return MakeSimpleNode(func(node *SimpleNode[any]) {
+			for i, time := range times {
+				node.AdvanceToTime(NewTime(time))
+				Printf(node, "line %d\n", i)
+			}
+		}, (*any)(nil))
+	}
+	first := makePrinter(3, 5, 5, 10)
+	second := makePrinter(1, 5, 8)
+	ctx.AddChild(first)
+	ctx.AddChild(second)
+
+	var buffer bytes.Buffer
+	log := MakeOutputLog(&buffer)
+	if _, err := Simulate(ctx, WithOutputLog(log)); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Unnamed nodes are named by their place in the graph.
+	a, b := "*core.basicContext(id=0).*core.SimpleNode[interface {}][0]", "*core.basicContext(id=0).*core.SimpleNode[interface {}][1]"
+	expected := strings.Join([]string{
+		fmt.Sprintf("[1] %s: line 0", b),
+		fmt.Sprintf("[3] %s: line 0", a),
+		fmt.Sprintf("[5] %s: line 1", a),
+		fmt.Sprintf("[5] %s: line 2", a),
+		fmt.Sprintf("[5] %s: line 1", b),
+		fmt.Sprintf("[8] %s: line 2", b),
+		fmt.Sprintf("[10] %s: line 3", a),
+	}, "\n") + "\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buffer.String())
+	}
+}
+
+func TestOutputLogFlushesAtHorizon(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	var buffer bytes.Buffer
+	log := MakeOutputLog(&buffer)
+	var seen string
+	early := MakeSimpleNode(func(node *SimpleNode[any]) {
+		Println(node, "early")
+	}, (*any)(nil))
+	late := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.AdvanceToTime(NewTime(10))
+		// Once early has finished, its line is safe to write.
+		WaitUntil(node, early, NewTime(10))
+		Println(node, "late")
+		log.mutex.Lock()
+		seen = buffer.String()
+		log.mutex.Unlock()
+	}, (*any)(nil))
+	ctx.AddChild(early)
+	ctx.AddChild(late)
+	if _, err := Simulate(ctx, WithOutputLog(log)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprintf("[0] %s: early\n", log.name(early)); seen != expected {
+		t.Errorf("Expected %q to be written before the simulation ended, got %q", expected, seen)
+	}
+}
+
+func TestOutputLogFlushesWithoutPrinting(t *testing.T) {
+	for _, backend := range backends {
+		ctx := MakePrimitiveContext(nil)
+		var buffer bytes.Buffer
+		log := MakeOutputLog(&buffer)
+		var seen string
+		quiet := MakeSimpleNode(func(node *SimpleNode[any]) {
+			Println(node, "only line")
+			node.IncrCycles(NewTime(100))
+		}, (*any)(nil))
+		other := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.AdvanceToTime(NewTime(60))
+		}, (*any)(nil))
+		watcher := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.AdvanceToTime(NewTime(50))
+			WaitUntil(node, quiet, NewTime(50))
+			WaitUntil(node, other, NewTime(60))
+			// Nobody prints again, so the line has to be written as the simulation goes on.
+			deadline := time.Now().Add(10 * outputFlushInterval)
+			for {
+				log.mutex.Lock()
+				seen = buffer.String()
+				log.mutex.Unlock()
+				if seen != "" || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(outputFlushInterval / 10)
+			}
+		}, (*any)(nil))
+		ctx.AddChild(quiet)
+		ctx.AddChild(watcher)
+		ctx.AddChild(other)
+		if _, err := Simulate(ctx, WithBackend(backend), WithOutputLog(log)); err != nil {
+			t.Fatal(err)
+		}
+		if expected := fmt.Sprintf("[0] %s: only line\n", log.name(quiet)); seen != expected {
+			t.Errorf("%v: expected %q to be written before the simulation ended, got %q", backend, expected, seen)
+		}
+	}
+}
+
+// Two runs of the same simulation print exactly the same log.
+func TestOutputLogIsReproducible(t *testing.T) {
+	run := func() string {
+		ctx := MakePrimitiveContext(nil)
+		inner := MakePrimitiveContext(ctx)
+		for i := 0; i < 3; i++ {
+			i := i
+			node := MakeSimpleNode(func(node *SimpleNode[any]) {
+				node.IncrCycles(NewTime(int64(i)))
+				Printf(node, "node %d\n", i)
+			}, (*any)(nil))
+			if i == 0 {
+				ctx.AddChild(node)
+			} else {
+				inner.AddChild(node)
+			}
+		}
+		var buffer bytes.Buffer
+		if _, err := Simulate(ctx, WithOutputLog(MakeOutputLog(&buffer))); err != nil {
+			t.Fatal(err)
+		}
+		return buffer.String()
+	}
+	first, second := run(), run()
+	if first != second {
+		t.Errorf("Expected the same output from both runs, got:\n%s\nand:\n%s", first, second)
+	}
+	if strings.Contains(first, "0x") {
+		t.Errorf("Expected no addresses in the output, got:\n%s", first)
+	}
+}
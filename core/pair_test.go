@@ -0,0 +1,18 @@
+package core
+
+import "testing"
+
+var backends = []Backend{ParallelBackend, DeterministicBackend}
+
+// runPair connects producer to consumer through channel, and simulates the two of them with backend.
+func runPair(t *testing.T, backend Backend, channel *CommunicationChannel, producer, consumer *SimpleNode[any]) {
+	t.Helper()
+	ctx := MakePrimitiveContext(nil)
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+	if _, err := Simulate(ctx, WithBackend(backend)); err != nil {
+		t.Fatalf("%v: %v", backend, err)
+	}
+}
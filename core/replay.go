@@ -0,0 +1,335 @@
+package core
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+	"github.com/stanford-ppl/DAM/utils"
+)
+
+func init() {
+	// Other types carried by recorded channels need to be registered with gob.Register before saving or loading.
+	gob.Register(datatypes.Bit{})
+	gob.Register(datatypes.FixedPoint{})
+	gob.Register(datatypes.Vector[datatypes.Bit]{})
+	gob.Register(datatypes.Vector[datatypes.FixedPoint]{})
+}
+
+// A RecordedElement is an element that went through a channel.
+type RecordedElement struct {
+	// The time of the sender when it was enqueued
+	Sent    Time
+	Element ChannelElement
+	// When it was dequeued, or infinite if it never was
+	Received Time
+}
+
+// A ChannelRecording is everything that went through one of a node's channels, along with how it was set up.
+type ChannelRecording struct {
+	Channel      string
+	Type         string
+	Capacity     int
+	Lookahead    Time
+	Synchronizer int64
+	WireLatency  int64
+	Bandwidth    int64
+	// How many of the elements were initial tokens, which come first
+	InitialTokens int
+	// The clock of the context on the other end of the channel, and its lookahead
+	PeerClock     *ClockDomain
+	PeerLookahead Time
+	Elements      []RecordedElement
+}
+
+// A Recording holds the traffic through every channel of a node, so that it can be replayed without the rest of its graph.
+type Recording struct {
+	Node        string
+	Inputs      []ChannelRecording
+	Outputs     []ChannelRecording
+	InputPorts  map[string]int
+	OutputPorts map[string]int
+}
+
+// Save writes the recording with gob.
+func (recording *Recording) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(recording)
+}
+
+func LoadRecording(r io.Reader) (*Recording, error) {
+	recording := new(Recording)
+	if err := gob.NewDecoder(r).Decode(recording); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+type channelLog struct {
+	mutex    sync.Mutex
+	elements []RecordedElement
+	// The index of the next element to be dequeued
+	next int
+}
+
+// The record methods are no-ops if the channel isn't recorded.
+
+func (log *channelLog) recordEnqueue(sent *Time, ce *ChannelElement) {
+	if log == nil {
+		return
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	element := RecordedElement{Element: *ce, Received: *InfiniteTime()}
+	element.Sent.Set(sent)
+	log.elements = append(log.elements, element)
+}
+
+func (log *channelLog) recordDequeue(ce *ChannelElement) {
+	if log == nil {
+		return
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.elements[log.next].Received.Set(&ce.Time)
+	log.next++
+}
+
+func (cchan *CommunicationChannel) startRecording() *CommunicationChannel {
+	if cchan.record == nil {
+		cchan.record = new(channelLog)
+	}
+	return cchan
+}
+
+// recording describes the channel and what has gone through it so far, with peer at the other end.
+func (cchan *CommunicationChannel) recording(peer ContextView) ChannelRecording {
+	cchan.record.mutex.Lock()
+	defer cchan.record.mutex.Unlock()
+	recording := ChannelRecording{
+		Channel:       cchan.endpointString(),
+		Type:          cchan.elemType,
+		Capacity:      cchan.capacity,
+		Synchronizer:  cchan.synchronizer,
+		WireLatency:   cchan.wireLatency,
+		Bandwidth:     cchan.bandwidth,
+		InitialTokens: len(cchan.initialTokens),
+		PeerClock:     clockDomainOf(peer),
+		Elements:      append([]RecordedElement(nil), cchan.record.elements...),
+	}
+	recording.Lookahead.Set(&cchan.minLatency)
+	if hasLookahead, ok := peer.(HasLookahead); ok {
+		recording.PeerLookahead.Set(hasLookahead.Lookahead())
+	}
+	return recording
+}
+
+// A NodeRecorder records the channels of a node while it is simulated.
+type NodeRecorder struct {
+	node Context
+	llio *LowLevelIO
+}
+
+// RecordNode starts recording every element which goes through the channels of node.
+// It should be called once the node is connected, but before the simulation starts.
+func RecordNode(node Context) *NodeRecorder {
+	hasIO, ok := node.(hasLowLevelIO)
+	if !ok {
+		panic(fmt.Sprintf("Cannot record %s, since it doesn't have any channels", CtxToString(node)))
+	}
+	llio := hasIO.lowLevelIO()
+	for _, channel := range llio.inputChannels {
+		channel.startRecording()
+	}
+	for _, channel := range llio.outputChannels {
+		channel.startRecording()
+	}
+	return &NodeRecorder{node: node, llio: llio}
+}
+
+// Recording returns everything recorded so far.
+func (recorder *NodeRecorder) Recording() *Recording {
+	recording := &Recording{
+		Node:        CtxToString(recorder.node),
+		InputPorts:  recorder.llio.inputPorts,
+		OutputPorts: recorder.llio.outputPorts,
+	}
+	for _, channel := range recorder.llio.inputChannels {
+		recording.Inputs = append(recording.Inputs, channel.recording(channel.srcCtx))
+	}
+	for _, channel := range recorder.llio.outputChannels {
+		recording.Outputs = append(recording.Outputs, channel.recording(channel.dstCtx))
+	}
+	return recording
+}
+
+// A ReplayMismatch is a difference between the original run of a node and its replay.
+type ReplayMismatch struct {
+	// Whether the channel is one of the node's outputs, and its index
+	Output  bool
+	Channel int
+	Index   int
+	// Either may be nil, if the element was only seen in one of the runs
+	Expected *RecordedElement
+	Got      *RecordedElement
+	Reason   string
+}
+
+func (mismatch *ReplayMismatch) Error() string {
+	kind := "input"
+	if mismatch.Output {
+		kind = "output"
+	}
+	return fmt.Sprintf("%s %d, element %d: %s (expected %s, got %s)", kind, mismatch.Channel, mismatch.Index, mismatch.Reason,
+		describeRecorded(mismatch.Expected), describeRecorded(mismatch.Got))
+}
+
+func describeRecorded(element *RecordedElement) string {
+	if element == nil {
+		return "nothing"
+	}
+	return fmt.Sprintf("%v at %v, sent at %v and received at %v", element.Element.Data, &element.Element.Time, &element.Sent, &element.Received)
+}
+
+// sameData compares payloads, falling back to how they print, since equal big.Ints may be laid out differently.
+func sameData(a, b datatypes.DAMType) bool {
+	return reflect.DeepEqual(a, b) || fmt.Sprintf("%T %v", a, a) == fmt.Sprintf("%T %v", b, b)
+}
+
+// compareRecordings reports every way that got differs from expected.
+func compareRecordings(output bool, expected, got []ChannelRecording) (mismatches []error) {
+	for channel := range expected {
+		want, have := expected[channel].Elements, got[channel].Elements
+		for i := 0; i < len(want) || i < len(have); i++ {
+			mismatch := &ReplayMismatch{Output: output, Channel: channel, Index: i}
+			switch {
+			case i >= len(have):
+				mismatch.Expected, mismatch.Reason = &want[i], "missing element"
+			case i >= len(want):
+				mismatch.Got, mismatch.Reason = &have[i], "extra element"
+			default:
+				mismatch.Expected, mismatch.Got = &want[i], &have[i]
+				switch {
+				case output && !sameData(want[i].Element.Data, have[i].Element.Data):
+					mismatch.Reason = "different data"
+				case output && want[i].Element.Time.Cmp(&have[i].Element.Time) != 0:
+					mismatch.Reason = "different time"
+				case want[i].Received.Cmp(&have[i].Received) != 0:
+					mismatch.Reason = "received at a different time"
+				default:
+					continue
+				}
+			}
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return
+}
+
+// Replay simulates node on its own, feeding it the inputs from recording and draining its outputs at the same times
+// that they were originally dequeued. node should be freshly built, without any channels connected.
+// Every divergence from the recording is returned as a *ReplayMismatch, joined with any error from the simulation.
+// The replay uses the DeterministicBackend unless opts say otherwise, so that it is reproducible.
+func Replay(node Context, recording *Recording, opts ...SimulationOption) error {
+	hasIO, ok := node.(hasLowLevelIO)
+	if !ok {
+		return fmt.Errorf("Cannot replay %s, since it doesn't have any channels", CtxToString(node))
+	}
+	llio := hasIO.lowLevelIO()
+	if len(llio.inputChannels) != 0 || len(llio.outputChannels) != 0 {
+		return fmt.Errorf("Cannot replay %s, since it already has channels connected", CtxToString(node))
+	}
+
+	root := MakePrimitiveContext(nil)
+	makeChannel := func(recorded *ChannelRecording) *CommunicationChannel {
+		channel := newCommunicationChannel(recorded.Capacity, recorded.Type)
+		channel.synchronizer = recorded.Synchronizer
+		channel.minLatency.Set(&recorded.Lookahead)
+		if recorded.InitialTokens > 0 {
+			tokens := make([]ChannelElement, recorded.InitialTokens)
+			for i := range tokens {
+				tokens[i] = recorded.Elements[i].Element
+			}
+			channel.SetInitialTokens(tokens...)
+		}
+		return channel.startRecording()
+	}
+	var mutex sync.Mutex
+	var late []error
+	for i := range recording.Inputs {
+		i, recorded := i, &recording.Inputs[i]
+		channel := makeChannel(recorded)
+		// The recorded elements already made it across the link, so they aren't delayed again. Instead the channel
+		// promises the destination everything that the source and the link did, so that it can skip ahead the same.
+		utils.Max[*Time](&channel.minLatency, &recorded.PeerLookahead, &channel.minLatency)
+		channel.minLatency.Add(&channel.minLatency, recorded.PeerClock.CyclesToTime(NewTime(recorded.WireLatency)))
+		// Sources send as early as the channel has room, rather than at the time each element was originally sent.
+		// Otherwise the node might check the channel between the source reaching that time and enqueuing the element.
+		source := MakeSimpleNode(func(source *SimpleNode[any]) {
+			for j := recorded.InitialTokens; j < len(recorded.Elements); j++ {
+				AdvanceUntilCanEnqueue(source, 0)
+				element := recorded.Elements[j].Element
+				// If the node has fallen behind, the channel may not have had room in time to send the element
+				// as early as it was. Send it as soon as possible instead, and report it.
+				earliest := new(Time).Add(source.TickLowerBound(), channel.sourceLookahead())
+				if element.Time.Cmp(earliest) < 0 {
+					got := RecordedElement{Element: element, Received: *InfiniteTime()}
+					got.Element.Time.Set(earliest)
+					got.Sent.Set(source.TickLowerBound())
+					mutex.Lock()
+					late = append(late, &ReplayMismatch{Channel: i, Index: j, Expected: &recorded.Elements[j], Got: &got,
+						Reason: "sent late, since the channel was still full"})
+					mutex.Unlock()
+					element.Time.Set(earliest)
+				}
+				source.OutputChannel(0).Enqueue(element)
+			}
+		}, (*any)(nil))
+		source.SetClockDomain(recorded.PeerClock)
+		source.AddOutputChannel(channel)
+		llio.AddInputChannel(source.outputChannels[0])
+		root.AddChild(source)
+	}
+	for i := range recording.Outputs {
+		recorded := &recording.Outputs[i]
+		sink := MakeSimpleNode(func(sink *SimpleNode[any]) {
+			for _, element := range recorded.Elements {
+				if element.Received.IsInf() {
+					// The rest were left in the channel.
+					return
+				}
+				sink.AdvanceToTime(&element.Received)
+				if DequeueInputChansByID(sink, 0)[0].Status == Closed {
+					return
+				}
+			}
+			// Drain anything extra, so that it shows up as a mismatch.
+			for DequeueInputChansByID(sink, 0)[0].Status != Closed {
+			}
+		}, (*any)(nil))
+		sink.SetClockDomain(recorded.PeerClock)
+		channel := makeChannel(recorded)
+		channel.wireLatency, channel.bandwidth = recorded.WireLatency, recorded.Bandwidth
+		sink.AddInputChannel(channel)
+		llio.AddOutputChannel(sink.inputChannels[0])
+		root.AddChild(sink)
+	}
+	for name, id := range recording.InputPorts {
+		llio.In(name)
+		llio.inputPorts[name] = id
+	}
+	for name, id := range recording.OutputPorts {
+		llio.Out(name)
+		llio.outputPorts[name] = id
+	}
+	root.AddChild(node)
+
+	_, err := Simulate(root, append([]SimulationOption{WithBackend(DeterministicBackend)}, opts...)...)
+	replayed := (&NodeRecorder{node: node, llio: llio}).Recording()
+	mismatches := append(append([]error{err}, late...), compareRecordings(false, recording.Inputs, replayed.Inputs)...)
+	mismatches = append(mismatches, compareRecordings(true, recording.Outputs, replayed.Outputs)...)
+	return errors.Join(mismatches...)
+}
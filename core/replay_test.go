@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+var replayType = datatypes.FixedPointType{Signed: true, Integer: 16, Fraction: 0}
+
+// makeIncrementer adds increment to each input, taking latency cycles to do so.
+func makeIncrementer(increment, latency int64) *SimpleNode[any] {
+	node := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for {
+			input := DequeueInputChansByID(node, node.InputID("in"))[0]
+			if input.Status == Closed {
+				return
+			}
+			node.IncrCycles(NewTime(latency))
+			value := datatypes.FixedPoint{Tp: replayType}
+			value.SetInt64(input.Data.(datatypes.FixedPoint).ToInt().Int64() + increment)
+			AdvanceUntilCanEnqueue(node, node.OutputID("out"))
+			node.OutputChannel(node.OutputID("out")).Enqueue(MakeChannelElement(node.TickLowerBound(), value))
+		}
+	}, (*any)(nil))
+	node.In("in")
+	node.Out("out")
+	return node
+}
+
+// recordIncrementer records an incrementer between a source, which sends lookahead cycles ahead on a channel
+// with the given options, and a slow sink.
+func recordIncrementer(t *testing.T, lookahead int64, options ...ChannelOption) *Recording {
+	graph := MakeGraph()
+	source := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := int64(0); i < 4; i++ {
+			value := datatypes.FixedPoint{Tp: replayType}
+			value.SetInt64(i)
+			AdvanceUntilCanEnqueue(node, node.OutputID("out"))
+			sent := new(Time).Add(node.TickLowerBound(), NewTime(lookahead))
+			node.OutputChannel(node.OutputID("out")).Enqueue(MakeChannelElement(sent, value))
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	incrementer := makeIncrementer(1, 2)
+	// The sink is slow enough to stall the incrementer.
+	sink := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for DequeueInputChansByID(node, node.InputID("in"))[0].Status != Closed {
+			node.IncrCycles(NewTime(5))
+		}
+	}, (*any)(nil))
+	graph.Add(source, incrementer, sink)
+	graph.Connect(source.Out("out"), incrementer.In("in"), 2, options...).SetLookahead(NewTime(lookahead))
+	graph.Connect(incrementer.Out("out"), sink.In("in"), 1)
+
+	recorder := RecordNode(incrementer)
+	if _, err := Simulate(graph, WithBackend(DeterministicBackend)); err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	if err := recorder.Recording().Save(&file); err != nil {
+		t.Fatal(err)
+	}
+	recording, err := LoadRecording(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recording.Inputs) != 1 || len(recording.Outputs) != 1 || len(recording.Outputs[0].Elements) != 4 {
+		t.Fatalf("Expected one input and one output of 4 elements, got %+v", recording)
+	}
+	return recording
+}
+
+func TestReplayMatches(t *testing.T) {
+	recording := recordIncrementer(t, 0)
+	if err := Replay(makeIncrementer(1, 2), recording); err != nil {
+		t.Errorf("Expected the replay to match, got %v", err)
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	recording := recordIncrementer(t, 0)
+
+	err := Replay(makeIncrementer(2, 2), recording)
+	var mismatch *ReplayMismatch
+	if !errors.As(err, &mismatch) || !mismatch.Output || mismatch.Index != 0 || mismatch.Reason != "different data" {
+		t.Errorf("Expected the values to diverge, got %v", err)
+	}
+
+	// Being slower shows up first as taking the second input late.
+	err = Replay(makeIncrementer(1, 3), recording)
+	if !errors.As(err, &mismatch) || mismatch.Output || mismatch.Index != 1 || mismatch.Reason != "received at a different time" {
+		t.Errorf("Expected the timing to diverge, got %v", err)
+	}
+	t.Log(err)
+}
+
+func TestReplayLateInputs(t *testing.T) {
+	recording := recordIncrementer(t, 3, WithWireLatency(1))
+	if err := Replay(makeIncrementer(1, 2), recording); err != nil {
+		t.Errorf("Expected the replay to match, got %v", err)
+	}
+
+	// A much slower incrementer keeps the input channel full past when the source promised to send the rest.
+	err := Replay(makeIncrementer(1, 10), recording)
+	var nodeErr *NodeError
+	if errors.As(err, &nodeErr) {
+		t.Fatalf("Expected the replay to finish, got %v", err)
+	}
+	var mismatch *ReplayMismatch
+	if !errors.As(err, &mismatch) || mismatch.Output || mismatch.Reason != "sent late, since the channel was still full" {
+		t.Errorf("Expected an input to be sent late, got %v", err)
+	}
+	t.Log(err)
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// How often the parallel executor writes out lines which were held back by the OutputLog.
+const outputFlushInterval = 100 * time.Millisecond
+
+// A Backend determines how the contexts of a simulation are executed.
+type Backend uint8
+
+const (
+	// Each context runs freely on its own goroutine.
+	ParallelBackend Backend = iota
+	// Contexts take turns on a single thread, always resuming the one with the lowest TickLowerBound.
+	// Runs are reproducible, which makes this useful for debugging and golden tests.
+	// Contexts must only block through the simulator (channels, WaitUntil), since nothing else can run in the meantime.
+	DeterministicBackend
+)
+
+func (backend Backend) String() string {
+	switch backend {
+	case ParallelBackend:
+		return "Parallel"
+	case DeterministicBackend:
+		return "Deterministic"
+	}
+	return "X"
+}
+
+// A task is a single context goroutine.
+type task struct {
+	ctx Context
+
+	resume  chan struct{}
+	waiting *waitRecord
+	done    bool
+}
+
+// An executor decides when each context goroutine gets to run.
+type executor interface {
+	// spawn registers tasks, whose goroutines must call start before running.
+	spawn(tasks []*task)
+	start(t *task)
+	exit(t *task)
+
+	park(rec *waitRecord)
+	unpark(rec *waitRecord)
+
+	// wait blocks the root of the simulation until done is closed.
+	wait(done <-chan struct{})
+}
+
+func newExecutor(sim *simulation, backend Backend) executor {
+	switch backend {
+	case DeterministicBackend:
+		return &deterministicExecutor{sim: sim, yield: make(chan struct{})}
+	default:
+		return &parallelExecutor{sim: sim, waiting: map[ContextView]*waitRecord{}}
+	}
+}
+
+// The parallelExecutor lets every task run at once, and counts the ones which are still running.
+// Once all of them are parked, it checks whether any of them can ever be woken up again.
+type parallelExecutor struct {
+	sim *simulation
+
+	mutex   sync.Mutex
+	active  int
+	waiting map[ContextView]*waitRecord
+}
+
+func (pe *parallelExecutor) spawn(tasks []*task) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	pe.active += len(tasks)
+}
+
+func (pe *parallelExecutor) start(*task) {}
+
+func (pe *parallelExecutor) exit(*task) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	pe.active--
+	pe.check()
+}
+
+func (pe *parallelExecutor) park(rec *waitRecord) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	pe.waiting[rec.ctx] = rec
+	pe.active--
+	pe.check()
+}
+
+func (pe *parallelExecutor) unpark(rec *waitRecord) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	delete(pe.waiting, rec.ctx)
+	pe.active++
+}
+
+func (pe *parallelExecutor) check() {
+	if pe.active > 0 {
+		return
+	}
+	if err := findDeadlock(pe.waiting); err != nil {
+		pe.sim.abort(err)
+	}
+}
+
+func (pe *parallelExecutor) wait(done <-chan struct{}) {
+	log := pe.sim.outputLog()
+	if log == nil {
+		<-done
+		return
+	}
+	// Lines are otherwise only written when something is printed, so a context which stops printing would hold
+	// back its last lines until the end.
+	ticker := time.NewTicker(outputFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			log.flush()
+		}
+	}
+}
+
+// The deterministicExecutor runs a single task at a time.
+// Tasks hand control back to it whenever they park or exit, and it then resumes the
+// runnable task with the lowest TickLowerBound, breaking ties by the order they were spawned in.
+// Since only one task runs at a time, its fields are only touched by whichever goroutine currently holds control.
+type deterministicExecutor struct {
+	sim *simulation
+
+	// Tasks are kept in the order they were spawned in.
+	tasks   []*task
+	current *task
+	yield   chan struct{}
+}
+
+func (de *deterministicExecutor) spawn(tasks []*task) {
+	for _, t := range tasks {
+		t.resume = make(chan struct{})
+	}
+	de.tasks = append(de.tasks, tasks...)
+}
+
+func (de *deterministicExecutor) start(t *task) {
+	<-t.resume
+}
+
+func (de *deterministicExecutor) exit(t *task) {
+	t.done = true
+	de.yield <- struct{}{}
+}
+
+func (de *deterministicExecutor) park(rec *waitRecord) {
+	t := de.current
+	t.waiting = rec
+	de.yield <- struct{}{}
+	<-t.resume
+}
+
+func (de *deterministicExecutor) unpark(*waitRecord) {
+	de.current.waiting = nil
+}
+
+func (de *deterministicExecutor) wait(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		next := de.pick()
+		if next == nil {
+			waiting := map[ContextView]*waitRecord{}
+			for _, t := range de.tasks {
+				waiting[t.waiting.ctx] = t.waiting
+			}
+			// Aborting makes all of the waiting tasks runnable, so that they can exit.
+			de.sim.abort(deadlockReport(waiting))
+			continue
+		}
+		de.current = next
+		next.resume <- struct{}{}
+		<-de.yield
+		de.sim.outputLog().flush()
+	}
+}
+
+// pick returns the runnable task which should go next, or nil if every task is stuck.
+func (de *deterministicExecutor) pick() (best *task) {
+	aborted := de.sim.isAborted()
+	live := de.tasks[:0]
+	var bestTime *Time
+	for _, t := range de.tasks {
+		if t.done {
+			continue
+		}
+		live = append(live, t)
+		runnable := t.waiting == nil || t.waiting.isReady() || (aborted && !t.waiting.waitsOnChildren)
+		if !runnable {
+			continue
+		}
+		tlb := t.ctx.TickLowerBound()
+		// The earliest spawned task wins ties.
+		if best == nil || tlb.Cmp(bestTime) < 0 {
+			best = t
+			bestTime = tlb
+		}
+	}
+	de.tasks = live
+	return
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+type eventLog struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (log *eventLog) add(format string, args ...any) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.events = append(log.events, fmt.Sprintf(format, args...))
+}
+
+// Builds a few producers feeding a summing node, which feeds a checker.
+// Every node appends to log whenever it does something, in the order it happens.
+func makeSummingPipeline(numProducers int, log *eventLog) (ParentContext, *SimpleNode[any]) {
+	ctx := MakePrimitiveContext(nil)
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+
+	summer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		inputs := make([]int, numProducers)
+		for i := range inputs {
+			inputs[i] = i
+		}
+		for iter := 0; iter < 8; iter++ {
+			sum := datatypes.FixedPoint{Tp: fpt}
+			for _, elem := range DequeueInputChansByID(node, inputs...) {
+				sum = datatypes.FixedAdd(sum, elem.Data.(datatypes.FixedPoint))
+			}
+			log.add("sum %v @ %v", sum.ToInt(), node.TickLowerBound())
+			AdvanceUntilCanEnqueue(node, 0)
+			outTime := node.TickLowerBound()
+			outTime.Add(outTime, NewTime(2))
+			node.OutputChannel(0).Enqueue(MakeChannelElement(outTime, sum))
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	ctx.AddChild(summer)
+
+	for p := 0; p < numProducers; p++ {
+		producerID := p
+		channel := MakeCommunicationChannel[datatypes.FixedPoint](2)
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			for iter := 0; iter < 8; iter++ {
+				val := datatypes.FixedPoint{Tp: fpt}
+				val.SetInt(big.NewInt(int64(iter * (producerID + 1))))
+				AdvanceUntilCanEnqueue(node, 0)
+				log.add("producer %d @ %v", producerID, node.TickLowerBound())
+				// Data is always stamped in the future, so that when it's seen doesn't depend on scheduling.
+				sendTime := node.TickLowerBound()
+				sendTime.Add(sendTime, OneTick)
+				node.OutputChannel(0).Enqueue(MakeChannelElement(sendTime, val))
+				node.IncrCycles(NewTime(int64(producerID + 1)))
+			}
+		}, (*any)(nil))
+		producer.AddOutputChannel(channel)
+		summer.AddInputChannel(channel)
+		ctx.AddChild(producer)
+	}
+
+	output := MakeCommunicationChannel[datatypes.FixedPoint](2)
+	checker := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for iter := 0; iter < 8; iter++ {
+			elem := DequeueInputChansByID(node, 0)[0]
+			log.add("checked %v @ %v", elem.Data.(datatypes.FixedPoint).ToInt(), &elem.Time)
+			node.IncrCycles(NewTime(3))
+		}
+	}, (*any)(nil))
+	summer.AddOutputChannel(output)
+	checker.AddInputChannel(output)
+	ctx.AddChild(checker)
+	return ctx, checker
+}
+
+func TestDeterministicMatchesParallel(t *testing.T) {
+	var parallelLog, deterministicLog eventLog
+	parallelCtx, parallelChecker := makeSummingPipeline(3, &parallelLog)
+	parallelResult, err := Simulate(parallelCtx, WithBackend(ParallelBackend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deterministicCtx, deterministicChecker := makeSummingPipeline(3, &deterministicLog)
+	deterministicResult, err := Simulate(deterministicCtx, WithBackend(DeterministicBackend))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parallelEnd := parallelResult.FinishTime(parallelChecker)
+	deterministicEnd := deterministicResult.FinishTime(deterministicChecker)
+	if parallelEnd.Cmp(deterministicEnd) != 0 {
+		t.Errorf("Checker finished at %v in parallel, but %v deterministically", parallelEnd, deterministicEnd)
+	}
+	if parallelResult.EndTime.Cmp(deterministicResult.EndTime) != 0 {
+		t.Errorf("Simulation ended at %v in parallel, but %v deterministically", parallelResult.EndTime, deterministicResult.EndTime)
+	}
+	checked := func(log *eventLog) (result []string) {
+		for _, line := range log.events {
+			if strings.HasPrefix(line, "checked") {
+				result = append(result, line)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(checked(&parallelLog), checked(&deterministicLog)) {
+		t.Errorf("Outputs differ:\n%v\n%v", checked(&parallelLog), checked(&deterministicLog))
+	}
+}
+
+func TestDeterministicIsReproducible(t *testing.T) {
+	var golden []string
+	for run := 0; run < 5; run++ {
+		var log eventLog
+		ctx, _ := makeSummingPipeline(4, &log)
+		if _, err := Simulate(ctx, WithBackend(DeterministicBackend)); err != nil {
+			t.Fatal(err)
+		}
+		if run == 0 {
+			golden = log.events
+			continue
+		}
+		if !reflect.DeepEqual(golden, log.events) {
+			t.Fatalf("Run %d differs from the first run:\n%v\n%v", run, golden, log.events)
+		}
+	}
+}
+
+func TestDeterministicDeadlock(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	var first, second *SimpleNode[any]
+	first = MakeSimpleNode(func(node *SimpleNode[any]) {
+		WaitUntil(node, second, NewTime(1))
+	}, (*any)(nil))
+	second = MakeSimpleNode(func(node *SimpleNode[any]) {
+		WaitUntil(node, first, NewTime(1))
+	}, (*any)(nil))
+	ctx.AddChild(first)
+	ctx.AddChild(second)
+
+	_, err := Simulate(ctx, WithBackend(DeterministicBackend))
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("Expected a deadlock, got %v", err)
+	}
+	if len(deadlock.Waits) != 2 {
+		t.Errorf("Expected both contexts to be reported: %v", deadlock)
+	}
+}
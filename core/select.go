@@ -0,0 +1,181 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/stanford-ppl/DAM/utils"
+)
+
+// A SelectCandidate is a bundle of input channels which all have an element ready.
+type SelectCandidate struct {
+	// The index of the bundle, as passed to Select
+	Bundle int
+	// The element at the front of each channel in the bundle
+	Elements []ChannelElement
+	// When the last of the elements arrived, which is when the bundle became ready
+	Time Time
+}
+
+// A SelectPolicy arbitrates between the bundles that are ready at the same time.
+type SelectPolicy interface {
+	// Choose returns the position in candidates of the winner. Candidates are in order of their Bundle,
+	// and there is always at least one of them.
+	Choose(candidates []SelectCandidate) int
+}
+
+// SelectPolicyFunc turns a function into a SelectPolicy.
+type SelectPolicyFunc func(candidates []SelectCandidate) int
+
+func (fn SelectPolicyFunc) Choose(candidates []SelectCandidate) int {
+	return fn(candidates)
+}
+
+// FixedPriorityPolicy always picks the lowest-indexed bundle.
+type FixedPriorityPolicy struct{}
+
+func (FixedPriorityPolicy) Choose([]SelectCandidate) int {
+	return 0
+}
+
+// OldestFirstPolicy picks the bundle which became ready earliest, breaking ties by index.
+type OldestFirstPolicy struct{}
+
+func (OldestFirstPolicy) Choose(candidates []SelectCandidate) (winner int) {
+	for i := range candidates {
+		if candidates[i].Time.Cmp(&candidates[winner].Time) < 0 {
+			winner = i
+		}
+	}
+	return
+}
+
+// RoundRobinPolicy picks the first ready bundle after the one which last won.
+// The zero value starts from the first bundle.
+type RoundRobinPolicy struct {
+	// The bundle after the one which last won
+	next int
+}
+
+func MakeRoundRobinPolicy() *RoundRobinPolicy {
+	return new(RoundRobinPolicy)
+}
+
+func (policy *RoundRobinPolicy) Choose(candidates []SelectCandidate) int {
+	winner := 0
+	for i, candidate := range candidates {
+		if candidate.Bundle >= policy.next {
+			winner = i
+			break
+		}
+	}
+	policy.next = candidates[winner].Bundle + 1
+	return winner
+}
+
+// WeightedRoundRobinPolicy shares wins between bundles in proportion to their weights, interleaving them as evenly
+// as it can. Bundles without a weight count as 1.
+type WeightedRoundRobinPolicy struct {
+	weights []int
+	// How far behind its share each bundle is
+	credit map[int]int
+}
+
+// MakeWeightedRoundRobinPolicy gives bundle i a weight of weights[i]. Every weight must be at least 1, since a
+// bundle with less would fall ever further behind its share, and panics otherwise.
+func MakeWeightedRoundRobinPolicy(weights ...int) *WeightedRoundRobinPolicy {
+	for i, weight := range weights {
+		if weight < 1 {
+			panic(fmt.Sprintf("Bundle %d has a weight of %d, but weights must be at least 1", i, weight))
+		}
+	}
+	return &WeightedRoundRobinPolicy{weights: weights, credit: map[int]int{}}
+}
+
+func (policy *WeightedRoundRobinPolicy) weight(bundle int) int {
+	if bundle < len(policy.weights) {
+		return policy.weights[bundle]
+	}
+	return 1
+}
+
+func (policy *WeightedRoundRobinPolicy) Choose(candidates []SelectCandidate) (winner int) {
+	total := 0
+	for i, candidate := range candidates {
+		weight := policy.weight(candidate.Bundle)
+		total += weight
+		policy.credit[candidate.Bundle] += weight
+		if policy.credit[candidate.Bundle] > policy.credit[candidates[winner].Bundle] {
+			winner = i
+		}
+	}
+	policy.credit[candidates[winner].Bundle] -= total
+	return
+}
+
+var (
+	_ SelectPolicy = FixedPriorityPolicy{}
+	_ SelectPolicy = OldestFirstPolicy{}
+	_ SelectPolicy = (*RoundRobinPolicy)(nil)
+	_ SelectPolicy = (*WeightedRoundRobinPolicy)(nil)
+	_ SelectPolicy = SelectPolicyFunc(nil)
+)
+
+// Select advances node until at least one of the bundles has an element on each of its channels,
+// and dequeues from the bundle that policy picks out of those that are ready.
+// Returns the index of the winning bundle along with its elements, or (-1, nil) once every bundle has a closed channel.
+func Select(node DeqInputChans, policy SelectPolicy, channelBundles ...[]int) (int, []CEWithStatus) {
+	defer during(node, Starved)()
+	// Fixed priority only needs the first ready bundle, so it doesn't wait to find out about the rest.
+	_, firstReady := policy.(FixedPriorityPolicy)
+	for {
+		curTime := node.TickLowerBound()
+		nextTime := InfiniteTime()
+		var candidates []SelectCandidate
+		for i, bundle := range channelBundles {
+			bundleNextTime := NewTime(0)
+			var ready bool = true
+			candidate := SelectCandidate{Bundle: i}
+		L:
+			for _, chanInd := range bundle {
+				cc := node.InputChannel(chanInd)
+				cE, status := cc.Peek()
+				switch status {
+				case Nothing:
+					tmp := Time{}
+					tmp.Add(&cE.Time, OneTick)
+					utils.Max[*Time](bundleNextTime, &tmp, bundleNextTime)
+					ready = false
+				case Closed:
+					// If the channel is closed, then there's no more data coming through.
+					ready = false
+					bundleNextTime = InfiniteTime()
+					break L
+				default:
+					if cE.Time.Cmp(curTime) > 0 {
+						ready = false
+					}
+					utils.Max[*Time](bundleNextTime, &cE.Time, bundleNextTime)
+					candidate.Elements = append(candidate.Elements, cE)
+				}
+			}
+			if ready {
+				candidate.Time.Set(bundleNextTime)
+				candidates = append(candidates, candidate)
+				if firstReady {
+					break
+				}
+			} else {
+				utils.Min[*Time](nextTime, bundleNextTime, nextTime)
+			}
+		}
+		if len(candidates) > 0 {
+			winner := candidates[policy.Choose(candidates)].Bundle
+			return winner, DequeueInputChansByID(node, channelBundles[winner]...)
+		}
+		if nextTime.IsInf() {
+			return -1, nil
+		}
+		// Otherwise, advance to nextTime, try again
+		node.AdvanceToTime(nextTime)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Each producer sends an element at each of the given times, and the consumer selects between them until they're all
+// closed, starting at time start and taking a tick for each element.
+// Returns the winners in order.
+func runSelect(t *testing.T, policy SelectPolicy, start int64, times ...[]int64) []int {
+	ctx := MakePrimitiveContext(nil)
+	var winners []int
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.AdvanceToTime(NewTime(start))
+		bundles := make([][]int, len(times))
+		for i := range bundles {
+			bundles[i] = []int{i}
+		}
+		for {
+			winner, elements := Select(node, policy, bundles...)
+			if winner < 0 {
+				return
+			}
+			if node.TickLowerBound().Cmp(&elements[0].Time) < 0 {
+				t.Errorf("Dequeued an element from %v at %v", &elements[0].Time, node.TickLowerBound())
+			}
+			winners = append(winners, winner)
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	for _, producerTimes := range times {
+		producerTimes := producerTimes
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			for _, time := range producerTimes {
+				node.OutputChannel(0).Enqueue(MakeChannelElement(NewTime(time), datatypes.Bit{}))
+			}
+		}, (*any)(nil))
+		channel := MakeCommunicationChannel[datatypes.Bit](len(producerTimes))
+		producer.AddOutputChannel(channel)
+		consumer.AddInputChannel(channel)
+		ctx.AddChild(producer)
+	}
+	ctx.AddChild(consumer)
+	if _, err := Simulate(ctx, WithBackend(DeterministicBackend)); err != nil {
+		t.Fatal(err)
+	}
+	return winners
+}
+
+func TestSelectPolicies(t *testing.T) {
+	allAtZero := [][]int64{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}
+	tests := []struct {
+		name     string
+		policy   SelectPolicy
+		start    int64
+		times    [][]int64
+		expected []int
+	}{
+		{"FixedPriority", FixedPriorityPolicy{}, 0, allAtZero, []int{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2}},
+		{"RoundRobin", MakeRoundRobinPolicy(), 0, allAtZero, []int{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2}},
+		{"RoundRobinZeroValue", &RoundRobinPolicy{}, 0, allAtZero, []int{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2}},
+		{"WeightedRoundRobin", MakeWeightedRoundRobinPolicy(2, 1, 1), 0, allAtZero, []int{0, 1, 2, 0, 0, 1, 2, 0, 1, 2, 1, 2}},
+		{"OldestFirst", OldestFirstPolicy{}, 10, [][]int64{{5, 6}, {3, 8}}, []int{1, 0, 0, 1}},
+		// The consumer starts before anything arrives, so it takes each element as it shows up.
+		{"OldestFirstInOrder", OldestFirstPolicy{}, 0, [][]int64{{5, 9}, {3, 7}}, []int{1, 0, 1, 0}},
+		{"Func", SelectPolicyFunc(func(candidates []SelectCandidate) int { return len(candidates) - 1 }), 0, allAtZero,
+			[]int{2, 2, 2, 2, 1, 1, 1, 1, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if winners := runSelect(t, test.policy, test.start, test.times...); !reflect.DeepEqual(winners, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, winners)
+			}
+		})
+	}
+}
+
+func TestWeightedRoundRobinRejectsWeights(t *testing.T) {
+	for _, weight := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a weight of %d to panic", weight)
+				}
+			}()
+			MakeWeightedRoundRobinPolicy(1, weight)
+		}()
+	}
+}
@@ -0,0 +1,421 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/stanford-ppl/DAM/utils"
+)
+
+// SimulationResult summarizes a completed simulation.
+type SimulationResult struct {
+	// The time at which the last context finished running.
+	EndTime *Time
+
+	// The time at which each context finished running, keyed by CtxToString.
+	FinishTimes map[string]*Time
+	// The clock domain of each context which has one, keyed by CtxToString.
+	ClockDomains map[string]*ClockDomain
+
+	// The stats of every channel, if WithChannelStats was given.
+	ChannelStats []*ChannelStats
+
+	// How each node spent its time.
+	Utilization []NodeUtilization
+}
+
+// FinishTime returns the time at which ctx finished running, or nil if it never finished.
+func (result *SimulationResult) FinishTime(ctx Context) *Time {
+	return result.FinishTimes[CtxToString(ctx)]
+}
+
+// FinishCycles returns the number of cycles of its own clock that ctx ran for, or nil if it never finished.
+func (result *SimulationResult) FinishCycles(ctx Context) *Time {
+	name := CtxToString(ctx)
+	finish, ok := result.FinishTimes[name]
+	if !ok {
+		return nil
+	}
+	return result.ClockDomains[name].TimeToCycles(finish)
+}
+
+// WriteFinishTable writes when each context finished, in cycles of its clock and in nanoseconds.
+func (result *SimulationResult) WriteFinishTable(w io.Writer) error {
+	names := make([]string, 0, len(result.FinishTimes))
+	for name := range result.FinishTimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "Node\tClock\tCycles\tNanoseconds\t")
+	for _, name := range names {
+		finish := result.FinishTimes[name]
+		domain := result.ClockDomains[name]
+		fmt.Fprintf(table, "%s\t%v\t%v\t%.3f\t\n", name, domain, domain.TimeToCycles(finish), TimeToNanoseconds(finish))
+	}
+	fmt.Fprintf(table, "End\t\t\t%.3f\t\n", TimeToNanoseconds(result.EndTime))
+	return table.Flush()
+}
+
+// A NodeError is a panic raised by a context while initializing or running.
+type NodeError struct {
+	Context string
+	Time    *Time
+	Cause   error
+	Stack   []byte
+}
+
+func (err *NodeError) Error() string {
+	return fmt.Sprintf("%s failed at time %v: %v", err.Context, err.Time, err.Cause)
+}
+
+func (err *NodeError) Unwrap() error {
+	return err.Cause
+}
+
+// A SimulationOption configures a call to Simulate.
+type SimulationOption func(*simulationConfig)
+
+type simulationConfig struct {
+	backend      Backend
+	channelStats bool
+	tracer       *ChromeTracer
+	outputLog    *OutputLog
+}
+
+// WithBackend selects how the contexts are executed. The default is the ParallelBackend.
+func WithBackend(backend Backend) SimulationOption {
+	return func(conf *simulationConfig) {
+		conf.backend = backend
+	}
+}
+
+// WithChannelStats enables stats on every channel, and returns them in the SimulationResult.
+func WithChannelStats() SimulationOption {
+	return func(conf *simulationConfig) {
+		conf.channelStats = true
+	}
+}
+
+// Simulate initializes and runs ctx and all of its children.
+// A panic in any context aborts the whole simulation, and is returned as a *NodeError.
+// If the contexts deadlock, a *DeadlockError is returned instead.
+// A *Graph is validated before it is initialized, and any problems with it are returned without running it.
+func Simulate(ctx ParentContext, opts ...SimulationOption) (SimulationResult, error) {
+	initialize := ctx.Init
+	if graph, ok := ctx.(*Graph); ok {
+		if err := graph.Validate(); err != nil {
+			return SimulationResult{}, err
+		}
+		// The graph is already known to be valid, so there's no need for Init to check it again.
+		initialize = graph.basicContext.Init
+	}
+	conf := simulationConfig{}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	if conf.channelStats {
+		walkChannels(ctx, func(c *CommunicationChannel) { c.EnableStats() })
+	}
+	if conf.tracer != nil {
+		// Channels look up the tracer once here, rather than on every element.
+		walkChannels(ctx, func(c *CommunicationChannel) { c.tracer = conf.tracer })
+		defer walkChannels(ctx, func(c *CommunicationChannel) { c.tracer = nil })
+	}
+	sim := newSimulation(conf)
+	bindSimulation(ctx, sim)
+	defer bindSimulation(ctx, nil)
+	conf.outputLog.start(ctx)
+	defer conf.outputLog.finish()
+
+	if sim.guard(ctx, initialize) && sim.err() == nil {
+		sim.guard(ctx, ctx.Run)
+	}
+	result := sim.result()
+	result.Utilization = CollectUtilization(ctx)
+	if conf.channelStats {
+		result.ChannelStats = CollectChannelStats(ctx)
+	}
+	return result, sim.err()
+}
+
+// A simulation holds the state shared by all of the context goroutines of a single run.
+// It is created by the root context and handed down to each child as it is spawned.
+type simulation struct {
+	exec executor
+
+	mutex       sync.Mutex
+	abortChan   chan struct{}
+	errs        []error
+	finishTimes map[string]*Time
+	domains     map[string]*ClockDomain
+
+	chromeTracer *ChromeTracer
+	output       *OutputLog
+}
+
+func newSimulation(conf simulationConfig) *simulation {
+	sim := &simulation{
+		abortChan:   make(chan struct{}),
+		finishTimes: map[string]*Time{},
+		domains:     map[string]*ClockDomain{},
+
+		chromeTracer: conf.tracer,
+		output:       conf.outputLog,
+	}
+	sim.exec = newExecutor(sim, conf.backend)
+	return sim
+}
+
+// abort cancels the simulation. Every context goroutine exits the next time it waits or advances its time.
+func (sim *simulation) abort(err error) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.errs = append(sim.errs, err)
+	if len(sim.errs) == 1 {
+		close(sim.abortChan)
+	}
+}
+
+func (sim *simulation) isAborted() bool {
+	select {
+	case <-sim.abortChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// exitIfAborted stops the calling goroutine if the simulation has been aborted.
+func (sim *simulation) exitIfAborted() {
+	if sim != nil && sim.isAborted() {
+		runtime.Goexit()
+	}
+}
+
+func (sim *simulation) err() error {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	if len(sim.errs) == 1 {
+		return sim.errs[0]
+	}
+	return errors.Join(sim.errs...)
+}
+
+// guard calls fn, turning a panic into a *NodeError for ctx that aborts the simulation.
+// Returns whether fn completed normally.
+func (sim *simulation) guard(ctx Context, fn func()) (completed bool) {
+	if sim != nil {
+		defer (func() {
+			// recover returns nil if fn called runtime.Goexit, which isn't a failure.
+			if r := recover(); r != nil {
+				sim.fail(ctx, r, debug.Stack())
+			}
+		})()
+	}
+	fn()
+	return true
+}
+
+func (sim *simulation) fail(ctx Context, r any, stack []byte) {
+	cause, ok := r.(error)
+	if !ok {
+		cause = fmt.Errorf("%v", r)
+	}
+	sim.abort(&NodeError{
+		Context: CtxToString(ctx),
+		Time:    ctx.TickLowerBound(),
+		Cause:   cause,
+		Stack:   stack,
+	})
+}
+
+func (sim *simulation) recordFinish(ctx Context) {
+	if sim == nil {
+		return
+	}
+	finish := ctx.TickLowerBound()
+	if finish.IsInf() {
+		// Parent contexts are only done once all of their children are cleaned up.
+		return
+	}
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	sim.finishTimes[CtxToString(ctx)] = finish
+	if domain := clockDomainOf(ctx); domain != nil {
+		sim.domains[CtxToString(ctx)] = domain
+	}
+}
+
+func (sim *simulation) result() (result SimulationResult) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	result.EndTime = NewTime(0)
+	result.FinishTimes = sim.finishTimes
+	result.ClockDomains = sim.domains
+	for _, finish := range sim.finishTimes {
+		utils.Max[*Time](result.EndTime, finish, result.EndTime)
+	}
+	return
+}
+
+// spawn registers the context goroutines for tasks before they are started.
+func (sim *simulation) spawn(tasks []*task) {
+	if sim != nil {
+		sim.exec.spawn(tasks)
+	}
+}
+
+// start blocks the new context goroutine for t until it is allowed to run.
+func (sim *simulation) start(t *task) {
+	if sim != nil {
+		sim.exec.start(t)
+	}
+}
+
+// exit records that the context goroutine for t has finished.
+func (sim *simulation) exit(t *task) {
+	if sim != nil {
+		sim.exec.exit(t)
+	}
+}
+
+// park records that the calling goroutine is about to block on rec.
+// If rec is already ready, nothing is recorded.
+// The returned channel is closed if the simulation is aborted while waiting.
+func (sim *simulation) park(rec *waitRecord) <-chan struct{} {
+	if sim == nil || rec.isReady() {
+		return nil
+	}
+	rec.parked = true
+	sim.exec.park(rec)
+	return sim.abortChan
+}
+
+func (sim *simulation) unpark(rec *waitRecord) {
+	if sim == nil || !rec.parked {
+		return
+	}
+	rec.parked = false
+	sim.exec.unpark(rec)
+}
+
+// await blocks on ch on behalf of rec.ctx, exiting the goroutine if the simulation is aborted.
+func (sim *simulation) await(rec *waitRecord, ch <-chan *Time) *Time {
+	select {
+	case t := <-ch:
+		return t
+	default:
+	}
+	abort := sim.park(rec)
+	select {
+	case t := <-ch:
+		sim.unpark(rec)
+		return t
+	case <-abort:
+		sim.unpark(rec)
+		runtime.Goexit()
+	}
+	return nil
+}
+
+// simulationBinder is implemented by contexts that keep track of the simulation they belong to.
+type simulationBinder interface {
+	bindSimulation(sim *simulation)
+	simulation() *simulation
+}
+
+func bindSimulation(ctx Context, sim *simulation) {
+	if binder, ok := ctx.(simulationBinder); ok {
+		binder.bindSimulation(sim)
+	}
+}
+
+// simulationOf finds the simulation that ctx is running in, walking up the hierarchy if needed.
+// Returns nil if ctx isn't part of a running simulation.
+func simulationOf(view ContextView) *simulation {
+	for view != nil {
+		if binder, ok := view.(simulationBinder); ok && binder.simulation() != nil {
+			return binder.simulation()
+		}
+		ctx, ok := view.(Context)
+		if !ok {
+			return nil
+		}
+		parent := ctx.ParentContext()
+		if parent == nil {
+			return nil
+		}
+		view = parent
+	}
+	return nil
+}
+
+// RunChildren runs each child on its own goroutine, cleaning it up once it finishes, and waits for all of them.
+// Parent contexts should use this rather than spawning goroutines themselves, so that the simulation
+// can keep track of which contexts are still able to make progress.
+func RunChildren(parent Context, children ...Context) {
+	if len(children) == 0 {
+		return
+	}
+	sim := simulationOf(parent)
+	var mutex sync.Mutex
+	remaining := len(children)
+	done := make(chan struct{})
+
+	tasks := utils.Map(children, func(child Context) *task { return &task{ctx: child} })
+	sim.spawn(tasks)
+	for _, t := range tasks {
+		bindSimulation(t.ctx, sim)
+		sim.traceContext(t.ctx)
+		go (func(t *task) {
+			defer (func() {
+				mutex.Lock()
+				remaining--
+				if remaining == 0 {
+					close(done)
+				}
+				mutex.Unlock()
+				sim.exit(t)
+			})()
+			sim.start(t)
+			sim.exitIfAborted()
+			if sim.guard(t.ctx, t.ctx.Run) {
+				sim.recordFinish(t.ctx)
+				sim.guard(t.ctx, t.ctx.Cleanup)
+			}
+		})(t)
+	}
+
+	if parent.ParentContext() == nil {
+		// The root isn't a context goroutine, so it only waits for the others.
+		if sim == nil {
+			<-done
+		} else {
+			sim.exec.wait(done)
+		}
+		return
+	}
+	// Nested parents always wait for their children, even when aborting, since the children exit on their own.
+	rec := &waitRecord{ctx: parent, waitsOnChildren: true, ready: func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return remaining == 0
+	}}
+	sim.park(rec)
+	<-done
+	sim.unpark(rec)
+}
+
+// WaitUntil blocks the waiter until target has reached at least time, and returns target's time.
+// Contexts should use this instead of receiving from BlockUntil directly, so that the simulation knows what they are waiting on.
+func WaitUntil(waiter Context, target ContextView, time *Time) *Time {
+	rec := &waitRecord{ctx: waiter, target: target, until: time}
+	return simulationOf(waiter).await(rec, target.BlockUntil(time))
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestSimulateResult(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Bit](2)
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			AdvanceUntilCanEnqueue(node, 0)
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			node.IncrCycles(NewTime(3))
+		}
+	}, nil)
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			DequeueInputChansByID(node, 0)
+			node.IncrCycles(OneTick)
+		}
+	}, nil)
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	result, err := Simulate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FinishTime(producer).Cmp(NewTime(12)) != 0 {
+		t.Errorf("Expected the producer to finish at 12, got %v", result.FinishTime(producer))
+	}
+	// The consumer reads at 0, 3, 6, and 9, and takes one more cycle to finish.
+	if result.FinishTime(consumer).Cmp(NewTime(10)) != 0 {
+		t.Errorf("Expected the consumer to finish at 10, got %v", result.FinishTime(consumer))
+	}
+	if result.EndTime.Cmp(NewTime(12)) != 0 {
+		t.Errorf("Expected the simulation to end at 12, got %v", result.EndTime)
+	}
+}
+
+func TestSimulateNodePanic(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Bit](2)
+	failure := errors.New("bad node")
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.IncrCycles(NewTime(7))
+		panic(failure)
+	}, nil)
+	// The consumer would otherwise wait forever for data that never arrives.
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for {
+			DequeueInputChansByID(node, 0)
+		}
+	}, nil)
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	_, err := Simulate(ctx)
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) {
+		t.Fatalf("Expected a NodeError, got %v", err)
+	}
+	t.Log(nodeErr)
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the error to wrap the panic value")
+	}
+	if nodeErr.Context != CtxToString(producer) {
+		t.Errorf("Expected the failure to be attributed to %s, got %s", CtxToString(producer), nodeErr.Context)
+	}
+	if nodeErr.Time.Cmp(NewTime(7)) != 0 {
+		t.Errorf("Expected the failure at time 7, got %v", nodeErr.Time)
+	}
+}
@@ -0,0 +1,254 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// ChannelStats summarizes the traffic through a channel which had stats enabled.
+type ChannelStats struct {
+	// The endpoints of the channel, as "src -> dst".
+	Channel  string
+	Type     string
+	Capacity int
+
+	// The number of elements sent, and their total size in bits.
+	Elements int64
+	Bits     *big.Int
+
+	// Occupancy[n] is the total time during which n elements were in the channel,
+	// from time 0 until the last element was dequeued.
+	// An element occupies the channel from when it is enqueued until it is dequeued.
+	Occupancy []*Time
+
+	// The total time the producer spent waiting because the channel was full, from the first
+	// time it saw IsFull until its next enqueue, or until it finished if it never enqueued again.
+	StalledCycles *Time
+	// The total time the consumer spent waiting because the channel was empty, from the first
+	// time it got Nothing until its next dequeue, or until it finished if it never dequeued again.
+	StarvedCycles *Time
+}
+
+// An occupancyEvent is an element entering (+1) or leaving (-1) a channel.
+type occupancyEvent struct {
+	time  Time
+	delta int
+}
+
+type channelStats struct {
+	mutex sync.Mutex
+
+	elements int64
+	bits     big.Int
+	events   []occupancyEvent
+
+	stalled, starved           Time
+	stalledSince, starvedSince *Time
+}
+
+// EnableStats starts collecting statistics on the channel, which can be retrieved with Stats.
+// It should be called before the simulation starts.
+func (cchan *CommunicationChannel) EnableStats() *CommunicationChannel {
+	if cchan.stats == nil {
+		cchan.stats = new(channelStats)
+	}
+	return cchan
+}
+
+// Stats returns the statistics collected so far, or nil if they were never enabled.
+func (cchan *CommunicationChannel) Stats() *ChannelStats {
+	if cchan.stats == nil {
+		return nil
+	}
+	stats := cchan.stats.summarize(finalTimeOf(cchan.srcCtx), finalTimeOf(cchan.dstCtx))
+	stats.Channel = cchan.endpointString()
+	stats.Type = cchan.elemType
+	stats.Capacity = cchan.capacity
+	return stats
+}
+
+// The record methods are no-ops if stats aren't enabled.
+
+func (stats *channelStats) recordEnqueue(srcTime *Time, ce *ChannelElement) {
+	if stats == nil {
+		return
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	stats.elements++
+	if ce.Data != nil {
+		stats.bits.Add(&stats.bits, ce.Data.Size())
+	}
+	// An element stamped in the past is counted from its timestamp, so that it never leaves before it arrives.
+	event := occupancyEvent{delta: 1}
+	event.time.Set(srcTime)
+	if ce.Time.Cmp(srcTime) < 0 {
+		event.time.Set(&ce.Time)
+	}
+	stats.events = append(stats.events, event)
+	if stats.stalledSince != nil {
+		stats.stalled.Add(&stats.stalled, new(Time).Sub(srcTime, stats.stalledSince))
+		stats.stalledSince = nil
+	}
+}
+
+func (stats *channelStats) recordDequeue(ce *ChannelElement) {
+	if stats == nil {
+		return
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	event := occupancyEvent{delta: -1}
+	event.time.Set(&ce.Time)
+	stats.events = append(stats.events, event)
+	if stats.starvedSince != nil {
+		stats.starved.Add(&stats.starved, new(Time).Sub(&ce.Time, stats.starvedSince))
+		stats.starvedSince = nil
+	}
+}
+
+func (stats *channelStats) recordFull(srcTime *Time) {
+	if stats == nil {
+		return
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.stalledSince == nil {
+		stats.stalledSince = new(Time).Set(srcTime)
+	}
+}
+
+func (stats *channelStats) recordEmpty(dstTime *Time) {
+	if stats == nil {
+		return
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.starvedSince == nil {
+		stats.starvedSince = new(Time).Set(dstTime)
+	}
+}
+
+// finalTimeOf returns how far ctx got, or nil if that isn't known.
+func finalTimeOf(ctx ContextView) *Time {
+	if ctx == nil {
+		return nil
+	}
+	if final, ok := ctx.(interface{ finalTime() *Time }); ok {
+		return final.finalTime()
+	}
+	return ctx.TickLowerBound()
+}
+
+// closeInterval adds the time from since until end to total, for a wait which was still going on at end.
+func closeInterval(total, since, end *Time) {
+	if since != nil && end != nil && !end.IsInf() && end.Cmp(since) > 0 {
+		total.Add(total, new(Time).Sub(end, since))
+	}
+}
+
+// summarize collects the stats, counting a stall or starvation that was never ended by an enqueue or dequeue
+// as lasting until the source or the destination got to srcEnd or dstEnd respectively.
+func (stats *channelStats) summarize(srcEnd, dstEnd *Time) *ChannelStats {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	result := &ChannelStats{
+		Elements:      stats.elements,
+		Bits:          new(big.Int).Set(&stats.bits),
+		StalledCycles: new(Time).Set(&stats.stalled),
+		StarvedCycles: new(Time).Set(&stats.starved),
+	}
+	closeInterval(result.StalledCycles, stats.stalledSince, srcEnd)
+	closeInterval(result.StarvedCycles, stats.starvedSince, dstEnd)
+
+	// Enqueues and dequeues are recorded by different contexts, so put them back in time order.
+	events := append([]occupancyEvent(nil), stats.events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if cmp := events[i].time.Cmp(&events[j].time); cmp != 0 {
+			return cmp < 0
+		}
+		// Arrivals go first, so that the occupancy never drops below 0.
+		return events[i].delta > events[j].delta
+	})
+	occupancy := 0
+	last := NewTime(0)
+	for _, event := range events {
+		if event.time.IsInf() {
+			break
+		}
+		for len(result.Occupancy) <= occupancy {
+			result.Occupancy = append(result.Occupancy, NewTime(0))
+		}
+		result.Occupancy[occupancy].Add(result.Occupancy[occupancy], new(Time).Sub(&event.time, last))
+		last.Set(&event.time)
+		occupancy += event.delta
+	}
+	return result
+}
+
+// CollectChannelStats returns the stats of every channel under root which has them enabled.
+func CollectChannelStats(root ParentContext) (result []*ChannelStats) {
+	seen := map[*CommunicationChannel]bool{}
+	walkContexts(root, func(ctx Context) {
+		hasIO, ok := ctx.(hasLowLevelIO)
+		if !ok {
+			return
+		}
+		// Every channel is reached through its producer, unless it doesn't have one.
+		llio := hasIO.lowLevelIO()
+		for _, channel := range append(append([]*CommunicationChannel(nil), llio.outputChannels...), llio.inputChannels...) {
+			if seen[channel] || channel.stats == nil {
+				continue
+			}
+			seen[channel] = true
+			result = append(result, channel.Stats())
+		}
+	})
+	return
+}
+
+// WriteChannelStatsCSV writes one row per channel.
+// The time spent at each occupancy is given in columns occupancy_0 through the largest occupancy reached.
+func WriteChannelStatsCSV(w io.Writer, stats []*ChannelStats) error {
+	maxOccupancy := 0
+	for _, stat := range stats {
+		if len(stat.Occupancy) > maxOccupancy {
+			maxOccupancy = len(stat.Occupancy)
+		}
+	}
+	writer := csv.NewWriter(w)
+	header := []string{"channel", "type", "capacity", "elements", "bits", "stalled_cycles", "starved_cycles"}
+	for i := 0; i < maxOccupancy; i++ {
+		header = append(header, fmt.Sprintf("occupancy_%d", i))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, stat := range stats {
+		row := []string{
+			stat.Channel,
+			stat.Type,
+			fmt.Sprint(stat.Capacity),
+			fmt.Sprint(stat.Elements),
+			stat.Bits.String(),
+			stat.StalledCycles.String(),
+			stat.StarvedCycles.String(),
+		}
+		for i := 0; i < maxOccupancy; i++ {
+			if i < len(stat.Occupancy) {
+				row = append(row, stat.Occupancy[i].String())
+			} else {
+				row = append(row, "0")
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/big"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Sends 4 elements, with the producer and consumer each taking their own time between them.
+func runStatsPipeline(t *testing.T, capacity int, producerDelay, consumerDelay int64) *ChannelStats {
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.FixedPoint](capacity)
+
+	var consumer *SimpleNode[any]
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			// Don't let the producer run ahead, so that the consumer has to wait on it.
+			WaitUntil(node, consumer, node.TickLowerBound())
+			AdvanceUntilCanEnqueue(node, 0)
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.FixedPoint{Tp: fpt}))
+			node.IncrCycles(NewTime(producerDelay))
+		}
+	}, (*any)(nil))
+	consumer = MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			DequeueInputChansByID(node, 0)
+			node.IncrCycles(NewTime(consumerDelay))
+		}
+	}, (*any)(nil))
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	result, err := Simulate(ctx, WithBackend(DeterministicBackend), WithChannelStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ChannelStats) != 1 {
+		t.Fatalf("Expected stats for one channel, got %d", len(result.ChannelStats))
+	}
+	stats := result.ChannelStats[0]
+	t.Logf("%+v", stats)
+	if stats.Elements != 4 || stats.Bits.Cmp(big.NewInt(4*32)) != 0 {
+		t.Errorf("Expected 4 elements of 32 bits, got %d elements and %v bits", stats.Elements, stats.Bits)
+	}
+	if len(stats.Occupancy) > capacity+1 {
+		t.Errorf("Occupancy can't exceed the capacity of %d: %v", capacity, stats.Occupancy)
+	}
+	return stats
+}
+
+func TestChannelStatsStalled(t *testing.T) {
+	stats := runStatsPipeline(t, 1, 1, 10)
+	if stats.StalledCycles.Cmp(NewTime(0)) <= 0 {
+		t.Errorf("Expected the producer to stall behind the slow consumer")
+	}
+	if stats.Occupancy[1].Cmp(NewTime(0)) <= 0 {
+		t.Errorf("Expected the channel to be occupied some of the time: %v", stats.Occupancy)
+	}
+}
+
+func TestChannelStatsStarved(t *testing.T) {
+	stats := runStatsPipeline(t, 4, 10, 1)
+	if stats.StarvedCycles.Cmp(NewTime(0)) <= 0 {
+		t.Errorf("Expected the consumer to starve behind the slow producer")
+	}
+	if stats.StalledCycles.Cmp(NewTime(0)) != 0 {
+		t.Errorf("Expected the producer never to stall, got %v", stats.StalledCycles)
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteChannelStatsCSV(&buffer, []*ChannelStats{stats}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0][0] != "channel" || records[1][3] != "4" {
+		t.Errorf("Unexpected CSV: %v", records)
+	}
+}
+
+// A wait that lasts until the end of the simulation still counts.
+func TestChannelStatsOpenAtEnd(t *testing.T) {
+	for _, backend := range backends {
+		stalled := MakeCommunicationChannel[datatypes.Bit](1).EnableStats()
+		starved := MakeCommunicationChannel[datatypes.Bit](1).EnableStats()
+		producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			// Nothing is ever dequeued, so the producer is stuck until it gives up.
+			if !node.OutputChannel(0).IsFull() {
+				t.Errorf("%v: expected the channel to be full", backend)
+			}
+			node.IncrCycles(NewTime(10))
+		}, (*any)(nil))
+		consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+			node.IncrCycles(NewTime(3))
+			// Nothing is ever sent, so the consumer starves until it gives up.
+			if _, status := node.InputChannel(1).Peek(); status != Nothing {
+				t.Errorf("%v: expected nothing to arrive, got %v", backend, status)
+			}
+			node.IncrCycles(NewTime(7))
+		}, (*any)(nil))
+		idle := MakeSimpleNode(func(node *SimpleNode[any]) {
+			// Move on without sending, but don't close the channel until the consumer is done with it.
+			node.IncrCycles(NewTime(100))
+			WaitUntil(node, consumer, NewTime(200))
+		}, (*any)(nil))
+		producer.AddOutputChannel(stalled)
+		consumer.AddInputChannel(stalled)
+		idle.AddOutputChannel(starved)
+		consumer.AddInputChannel(starved)
+		ctx := MakePrimitiveContext(nil)
+		ctx.AddChild(producer)
+		ctx.AddChild(consumer)
+		ctx.AddChild(idle)
+		if _, err := Simulate(ctx, WithBackend(backend)); err != nil {
+			t.Fatal(err)
+		}
+		if cycles := stalled.Stats().StalledCycles; cycles.Cmp(NewTime(10)) != 0 {
+			t.Errorf("%v: expected the producer to stall for 10 cycles, got %v", backend, cycles)
+		}
+		if cycles := starved.Stats().StarvedCycles; cycles.Cmp(NewTime(7)) != 0 {
+			t.Errorf("%v: expected the consumer to starve for 7 cycles, got %v", backend, cycles)
+		}
+	}
+}
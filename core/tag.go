@@ -1,6 +1,18 @@
 package core
 
-type TagType[D any, U any] struct{}
+import "fmt"
+
+// Tags carry control information, such as enables, stop tokens, or iteration indices, alongside the data on channels.
+// An output tag publishes updates of type U onto everything its node sends, and input tags of the same TagType
+// fold the updates their node receives into a state of type D.
+type TagType[D any, U any] struct {
+	// Tags are matched up by name, so it must be unique within a graph.
+	Name string
+}
+
+func MakeTagType[D any, U any](name string) TagType[D, U] {
+	return TagType[D, U]{Name: name}
+}
 
 type InputTagUpdater[D any, U any] interface {
 	CanRun(update U) bool
@@ -12,6 +24,7 @@ type InputTag[D any, U any] struct {
 
 	State D
 
+	// Used in place of an update from inputs which don't carry the tag.
 	Null    U
 	Updater InputTagUpdater[D, U]
 }
@@ -27,3 +40,146 @@ type OutputTag[D any, U any] struct {
 	Tag       TagType[D, U]
 	Publisher OutputTagPublisher[D, U]
 }
+
+// AnyInputTag is an InputTag of any type, so that nodes can hold several of them.
+type AnyInputTag interface {
+	tagName() string
+	null() any
+	canRun(update any) bool
+	update(updates []any, enabled bool)
+}
+
+// AnyOutputTag is an OutputTag of any type, so that nodes can hold several of them.
+type AnyOutputTag interface {
+	tagName() string
+	publish(state any) (update any, ok bool)
+}
+
+var (
+	_ AnyInputTag  = (*InputTag[any, any])(nil)
+	_ AnyOutputTag = (*OutputTag[any, any])(nil)
+)
+
+func (tag *InputTag[D, U]) tagName() string {
+	return tag.Tag.Name
+}
+
+func (tag *InputTag[D, U]) null() any {
+	return tag.Null
+}
+
+func (tag *InputTag[D, U]) typed(update any) U {
+	typed, ok := update.(U)
+	if !ok {
+		panic(fmt.Sprintf("Tag %s expected an update of type %T, got %T", tag.Tag.Name, tag.Null, update))
+	}
+	return typed
+}
+
+func (tag *InputTag[D, U]) canRun(update any) bool {
+	return tag.Updater.CanRun(tag.typed(update))
+}
+
+func (tag *InputTag[D, U]) update(updates []any, enabled bool) {
+	typed := make([]U, len(updates))
+	for i, update := range updates {
+		typed[i] = tag.typed(update)
+	}
+	tag.State = tag.Updater.Update(tag.State, typed, enabled)
+}
+
+func (tag *OutputTag[D, U]) tagName() string {
+	return tag.Tag.Name
+}
+
+func (tag *OutputTag[D, U]) publish(state any) (any, bool) {
+	if !tag.Publisher.HasPublish(state) {
+		return nil, false
+	}
+	return tag.Publisher.Publish(state), true
+}
+
+// The tags of a node, along with whatever its output tags published for the current firing.
+type tagSet struct {
+	inputs  []AnyInputTag
+	outputs []AnyOutputTag
+	pending map[string]any
+}
+
+// attach adds the pending updates to elementTags, without changing elementTags itself.
+func (tags *tagSet) attach(elementTags map[string]any) map[string]any {
+	if len(tags.pending) == 0 {
+		return elementTags
+	}
+	result := make(map[string]any, len(elementTags)+len(tags.pending))
+	for name, update := range tags.pending {
+		result[name] = update
+	}
+	// Tags set on the element by hand win.
+	for name, update := range elementTags {
+		result[name] = update
+	}
+	return result
+}
+
+func (prim *PrimitiveNode[T]) AddInputTag(tag AnyInputTag) {
+	prim.tags.inputs = append(prim.tags.inputs, tag)
+}
+
+func (prim *PrimitiveNode[T]) AddOutputTag(tag AnyOutputTag) {
+	prim.tags.outputs = append(prim.tags.outputs, tag)
+}
+
+func (prim *PrimitiveNode[T]) tagSet() *tagSet {
+	return &prim.tags
+}
+
+func (prim *PrimitiveNode[T]) tagState() any {
+	return prim.State
+}
+
+// A Tagged node has input and output tags which are updated each time it calls Fire.
+type Tagged interface {
+	DeqInputChans
+	tagSet() *tagSet
+	tagState() any
+}
+
+// Fire dequeues from each of the given inputs, and runs body on them unless an input tag can't run on its updates.
+// Every input tag is then updated with what each input carried, or its Null if an input didn't carry it.
+// Before body runs, the output tags publish from the node's state, and their updates are attached to
+// everything the node enqueues until the next firing.
+// Returns whether body ran.
+func Fire(node Tagged, body func(inputs []CEWithStatus), channelIndices ...int) (enabled bool) {
+	inputs := DequeueInputChansByID(node, channelIndices...)
+	tags := node.tagSet()
+	tags.pending = nil
+	enabled = true
+	updates := make([][]any, len(tags.inputs))
+	for i, tag := range tags.inputs {
+		updates[i] = make([]any, len(inputs))
+		for j, input := range inputs {
+			update, ok := input.Tags[tag.tagName()]
+			if !ok || input.Status != Ok {
+				update = tag.null()
+			}
+			updates[i][j] = update
+			enabled = enabled && tag.canRun(update)
+		}
+	}
+	for i, tag := range tags.inputs {
+		tag.update(updates[i], enabled)
+	}
+	if !enabled {
+		return
+	}
+
+	tags.pending = map[string]any{}
+	for _, tag := range tags.outputs {
+		if update, ok := tag.publish(node.tagState()); ok {
+			tags.pending[tag.tagName()] = update
+		}
+	}
+	body(inputs)
+	return
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Publishes whether the count in the node's state is even.
+type evenPublisher struct{}
+
+func (evenPublisher) Publish(state any) bool    { return *state.(*int)%2 == 0 }
+func (evenPublisher) HasPublish(state any) bool { return true }
+
+// Runs only when enabled, counting the firings it skipped.
+type enableUpdater struct{}
+
+func (enableUpdater) CanRun(enable bool) bool { return enable }
+func (enableUpdater) Update(skipped int, enables []bool, enabled bool) int {
+	if !enabled {
+		skipped++
+	}
+	return skipped
+}
+
+func TestEnableTag(t *testing.T) {
+	enable := MakeTagType[int, bool]("enable")
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.FixedPoint](4)
+	bits := MakeCommunicationChannel[datatypes.Bit](4)
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 8, Fraction: 0}
+
+	count := 0
+	producer := MakeSimpleNode(func(node *SimpleNode[int]) {
+		for i := 0; i < 4; i++ {
+			Fire(node, func([]CEWithStatus) {
+				value := datatypes.FixedPoint{Tp: fpt}
+				value.SetInt64(int64(*node.State))
+				node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), value))
+				node.OutputChannel(1).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+				*node.State++
+			})
+			node.IncrCycles(OneTick)
+		}
+	}, &count)
+	producer.AddOutputTag(&OutputTag[int, bool]{Tag: enable, Publisher: evenPublisher{}})
+
+	var received []int64
+	enableTag := &InputTag[int, bool]{Tag: enable, Null: true, Updater: enableUpdater{}}
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 4; i++ {
+			Fire(node, func(inputs []CEWithStatus) {
+				received = append(received, inputs[0].Data.(datatypes.FixedPoint).ToInt().Int64())
+			}, 0, 1)
+		}
+	}, (*any)(nil))
+	consumer.AddInputTag(enableTag)
+
+	producer.AddOutputChannel(channel)
+	producer.AddOutputChannel(bits)
+	consumer.AddInputChannel(channel)
+	consumer.AddInputChannel(bits)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+	if _, err := Simulate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 || received[0] != 0 || received[1] != 2 {
+		t.Errorf("Expected only the even values to be processed, got %v", received)
+	}
+	if enableTag.State != 2 {
+		t.Errorf("Expected 2 firings to be skipped, got %d", enableTag.State)
+	}
+}
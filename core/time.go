@@ -1,10 +1,36 @@
 package core
 
-import "math/big"
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+)
 
+// Time is held in an int64, and only switches over to a big.Int if it overflows.
+// Once allocated, a big.Int is never modified, so that copies of a Time can share it.
 type Time struct {
-	time big.Int
-	done bool // done means infinite time
+	small int64
+	large *big.Int // Set instead of small if the time doesn't fit in an int64
+	done  bool     // done means infinite time
+}
+
+func (t *Time) bigInt() *big.Int {
+	if t.large != nil {
+		return t.large
+	}
+	return big.NewInt(t.small)
+}
+
+// setBig stores value, going back to an int64 if it fits.
+func (t *Time) setBig(value *big.Int) {
+	if value.IsInt64() {
+		t.small = value.Int64()
+		t.large = nil
+	} else {
+		t.small = 0
+		t.large = value
+	}
 }
 
 func (t *Time) Cmp(ot *Time) int {
@@ -17,18 +43,100 @@ func (t *Time) Cmp(ot *Time) int {
 	if !t.done && ot.done {
 		return -1
 	}
-	return t.time.Cmp(&ot.time)
+	if t.large == nil && ot.large == nil {
+		switch {
+		case t.small < ot.small:
+			return -1
+		case t.small > ot.small:
+			return 1
+		}
+		return 0
+	}
+	return t.bigInt().Cmp(ot.bigInt())
 }
 
 func (t *Time) Set(ot *Time) *Time {
-	t.time.Set(&ot.time)
-	t.done = ot.done
+	*t = *ot
 	return t
 }
 
 func (t *Time) Add(a, b *Time) *Time {
-	t.time.Add(&a.time, &b.time)
-	t.done = a.done || b.done
+	done := a.done || b.done
+	if a.large == nil && b.large == nil {
+		sum := a.small + b.small
+		// Overflow happens only if both are the same sign, and the sum isn't.
+		if (a.small >= 0) != (b.small >= 0) || (sum >= 0) == (a.small >= 0) {
+			t.small, t.large, t.done = sum, nil, done
+			return t
+		}
+	}
+	t.setBig(new(big.Int).Add(a.bigInt(), b.bigInt()))
+	t.done = done
+	return t
+}
+
+// Sub sets t to a - b. b must be finite.
+func (t *Time) Sub(a, b *Time) *Time {
+	if b.done {
+		panic("Cannot subtract an infinite time")
+	}
+	done := a.done
+	if a.large == nil && b.large == nil {
+		// Overflow happens only if the signs differ, and the difference doesn't have the sign of a.
+		diff := a.small - b.small
+		if (a.small >= 0) == (b.small >= 0) || (diff >= 0) == (a.small >= 0) {
+			t.small, t.large, t.done = diff, nil, done
+			return t
+		}
+	}
+	t.setBig(new(big.Int).Sub(a.bigInt(), b.bigInt()))
+	t.done = done
+	return t
+}
+
+// mulInt64 sets t to a * factor.
+func (t *Time) mulInt64(a *Time, factor int64) *Time {
+	done := a.done
+	if factor == 1 {
+		return t.Set(a)
+	}
+	if a.large == nil {
+		product := a.small * factor
+		if a.small == 0 || (product/a.small == factor && !(a.small == -1 && factor == math.MinInt64)) {
+			t.small, t.large, t.done = product, nil, done
+			return t
+		}
+	}
+	t.setBig(new(big.Int).Mul(a.bigInt(), big.NewInt(factor)))
+	t.done = done
+	return t
+}
+
+// divInt64 sets t to a / divisor, rounded towards negative infinity, or towards positive infinity if ceil is set.
+// divisor must be positive.
+func (t *Time) divInt64(a *Time, divisor int64, ceil bool) *Time {
+	done := a.done
+	if divisor == 1 {
+		return t.Set(a)
+	}
+	if a.large == nil {
+		quotient, remainder := a.small/divisor, a.small%divisor
+		if remainder < 0 {
+			quotient--
+			remainder += divisor
+		}
+		if ceil && remainder != 0 {
+			quotient++
+		}
+		t.small, t.large, t.done = quotient, nil, done
+		return t
+	}
+	quotient, modulus := new(big.Int).DivMod(a.large, big.NewInt(divisor), new(big.Int))
+	if ceil && modulus.Sign() != 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	t.setBig(quotient)
+	t.done = done
 	return t
 }
 
@@ -39,8 +147,10 @@ func (t *Time) IsInf() bool {
 func (t *Time) String() string {
 	if t.done {
 		return "Inf"
+	} else if t.large != nil {
+		return t.large.String()
 	} else {
-		return t.time.String()
+		return strconv.FormatInt(t.small, 10)
 	}
 }
 
@@ -51,13 +161,38 @@ func InfiniteTime() *Time {
 }
 
 func NewTime(t int64) *Time {
-	time := Time{}
-	time.time.SetInt64(t)
-	return &time
+	return &Time{small: t}
+}
+
+// GobEncode lets a Time be saved, such as in a Recording.
+func (t Time) GobEncode() ([]byte, error) {
+	if t.done {
+		return []byte{1}, nil
+	}
+	value, err := t.bigInt().GobEncode()
+	return append([]byte{0}, value...), err
+}
+
+func (t *Time) GobDecode(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("Time: no data to decode")
+	}
+	*t = Time{done: data[0] == 1}
+	if t.done {
+		return nil
+	}
+	value := new(big.Int)
+	if err := value.GobDecode(data[1:]); err != nil {
+		return err
+	}
+	t.setBig(value)
+	return nil
 }
 
 func (t *Time) GetTime() big.Int {
-	return t.time
+	var result big.Int
+	result.Set(t.bigInt())
+	return result
 }
 
 var OneTick = NewTime(1)
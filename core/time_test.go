@@ -1,26 +1,75 @@
 package core
 
 import (
+	"math"
 	"math/big"
 	"testing"
 )
 
 func TestSimpleTime(t *testing.T) {
 	timeZero := NewTime(0)
-	if timeZero.time.Cmp(big.NewInt(0)) != 0 {
-		t.Errorf("Expected: %d, received: %d\n", 0, timeZero.time.Int64())
+	if timeZero.bigInt().Cmp(big.NewInt(0)) != 0 {
+		t.Errorf("Expected: %d, received: %d\n", 0, timeZero.bigInt().Int64())
 	}
 	if timeZero.done {
 		t.Errorf("Expected: not done, received: done\n")
 	}
 
 	timeOne := timeZero.Add(timeZero, NewTime(1))
-	if timeOne.time.Cmp(big.NewInt(1)) != 0 {
-		t.Errorf("Expected: %d, received: %d\n", 1, timeZero.time.Int64())
+	if timeOne.bigInt().Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Expected: %d, received: %d\n", 1, timeZero.bigInt().Int64())
 	}
 
 	timeTwo := timeOne.Add(timeOne, NewTime(1))
-	if timeTwo.time.Cmp(big.NewInt(2)) != 0 {
-		t.Errorf("Expected: %d, received: %d\n", 2, timeZero.time.Int64())
+	if timeTwo.bigInt().Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("Expected: %d, received: %d\n", 2, timeZero.bigInt().Int64())
+	}
+}
+
+func TestTimeSub(t *testing.T) {
+	diff := new(Time).Sub(NewTime(5), NewTime(7))
+	if diff.bigInt().Cmp(big.NewInt(-2)) != 0 {
+		t.Errorf("Expected: %d, received: %d\n", -2, diff.bigInt().Int64())
+	}
+	if !new(Time).Sub(InfiniteTime(), NewTime(7)).IsInf() {
+		t.Errorf("Expected: Inf, received: finite\n")
+	}
+}
+
+func TestTimeOverflow(t *testing.T) {
+	maxTime := NewTime(math.MaxInt64)
+	overflowed := new(Time).Add(maxTime, OneTick)
+	expected := new(big.Int).Add(big.NewInt(math.MaxInt64), big.NewInt(1))
+	if overflowed.bigInt().Cmp(expected) != 0 {
+		t.Errorf("Expected: %v, received: %v\n", expected, overflowed)
+	}
+	if overflowed.Cmp(maxTime) <= 0 || maxTime.Cmp(overflowed) >= 0 {
+		t.Errorf("Expected %v to be after %v\n", overflowed, maxTime)
+	}
+	// Copies share the big.Int, so updating one mustn't change the other.
+	copied := new(Time).Set(overflowed)
+	copied.Add(copied, OneTick)
+	if overflowed.bigInt().Cmp(expected) != 0 {
+		t.Errorf("Expected: %v, received: %v\n", expected, overflowed)
+	}
+	// Coming back into range goes back to an int64.
+	back := new(Time).Sub(copied, NewTime(2))
+	if back.large != nil || back.Cmp(maxTime) != 0 {
+		t.Errorf("Expected: %v, received: %v\n", maxTime, back)
+	}
+	underflowed := new(Time).Sub(NewTime(math.MinInt64), OneTick)
+	if underflowed.large == nil || underflowed.Cmp(NewTime(math.MinInt64)) >= 0 {
+		t.Errorf("Expected %v to be before %v\n", underflowed, math.MinInt64)
+	}
+	if !new(Time).Add(overflowed, InfiniteTime()).IsInf() {
+		t.Errorf("Expected: Inf, received: finite\n")
+	}
+}
+
+func BenchmarkTimeAddCmp(b *testing.B) {
+	time := NewTime(0)
+	limit := NewTime(int64(b.N))
+	for i := 0; time.Cmp(limit) < 0; i++ {
+		time.Add(time, OneTick)
 	}
 }
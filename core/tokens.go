@@ -0,0 +1,47 @@
+package core
+
+import "fmt"
+
+// WithInitialTokens seeds the channel with tokens, as with SetInitialTokens.
+func WithInitialTokens(tokens ...ChannelElement) ChannelOption {
+	return func(cchan *CommunicationChannel) {
+		cchan.SetInitialTokens(tokens...)
+	}
+}
+
+// SetInitialTokens replaces the tokens that the channel starts out holding, usually stamped at time 0.
+// The destination receives them before anything the source sends, as though they were sent before the simulation
+// started, so feedback loops can get going without any node sending them at start-up.
+// They take up capacity until they are dequeued like any other element, so a channel can't start out with more
+// of them than it holds. It must be called before the simulation starts.
+func (cchan *CommunicationChannel) SetInitialTokens(tokens ...ChannelElement) *CommunicationChannel {
+	if cchan.capacity == Rendezvous {
+		panic("A rendezvous channel can't hold initial tokens")
+	}
+	if cchan.capacity != Unbounded && len(tokens) > cchan.capacity {
+		panic(fmt.Sprintf("A channel which holds %d elements can't start with %d tokens", cchan.capacity, len(tokens)))
+	}
+	cchan.incrSRDelta(len(tokens) - len(cchan.initialTokens))
+	cchan.initialTokens = append([]ChannelElement(nil), tokens...)
+	return cchan
+}
+
+// InitialTokens returns how many tokens the channel started out with.
+func (cchan *CommunicationChannel) InitialTokens() int {
+	return len(cchan.initialTokens)
+}
+
+// sendInitialTokens puts the initial tokens in the channel, the first time that either end uses it.
+// By then the channel's stats and traces are set up, so they see the tokens arrive like any other element.
+func (cchan *CommunicationChannel) sendInitialTokens() {
+	cchan.initialOnce.Do(func() {
+		for i := range cchan.initialTokens {
+			token := &cchan.initialTokens[i]
+			cchan.stats.recordEnqueue(&token.Time, token)
+			cchan.trace.recordEnqueue(token)
+			cchan.record.recordEnqueue(&token.Time, token)
+			cchan.writes.Add(1)
+			cchan.underlying.send(*token)
+		}
+	})
+}
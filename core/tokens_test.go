@@ -0,0 +1,119 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+var tokenType = datatypes.FixedPointType{Signed: true, Integer: 32, Fraction: 0}
+
+func tokenValue(value int64) datatypes.FixedPoint {
+	result := datatypes.FixedPoint{Tp: tokenType}
+	result.SetInt64(value)
+	return result
+}
+
+// Builds a running sum, which adds each input to its previous result, fed back to itself.
+func makeAccumulatorLoop(tokens ...ChannelElement) (graph *Graph, received *[]int64) {
+	graph = MakeGraph()
+	received = new([]int64)
+	source := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := int64(1); i <= 4; i++ {
+			AdvanceUntilCanEnqueue(node, node.OutputID("out"))
+			node.OutputChannel(node.OutputID("out")).Enqueue(MakeChannelElement(node.TickLowerBound(), tokenValue(i)))
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	accumulator := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for {
+			inputs := DequeueInputChansByID(node, node.InputID("in"), node.InputID("previous"))
+			if inputs[0].Status == Closed {
+				return
+			}
+			sum := datatypes.FixedAdd(inputs[0].Data.(datatypes.FixedPoint), inputs[1].Data.(datatypes.FixedPoint))
+			outputs := []int{node.OutputID("out"), node.OutputID("next")}
+			AdvanceUntilCanEnqueue(node, outputs...)
+			for _, output := range outputs {
+				node.OutputChannel(output).Enqueue(MakeChannelElement(node.TickLowerBound(), sum))
+			}
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	sink := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for {
+			input := DequeueInputChansByID(node, node.InputID("in"))[0]
+			if input.Status == Closed {
+				return
+			}
+			*received = append(*received, input.Data.(datatypes.FixedPoint).ToInt().Int64())
+		}
+	}, (*any)(nil))
+	graph.Add(source, accumulator, sink)
+	graph.Connect(source.Out("out"), accumulator.In("in"), 2)
+	graph.Connect(accumulator.Out("next"), accumulator.In("previous"), 1, WithInitialTokens(tokens...))
+	graph.Connect(accumulator.Out("out"), sink.In("in"), 2)
+	return
+}
+
+func TestInitialTokensFeedback(t *testing.T) {
+	for _, backend := range backends {
+		graph, received := makeAccumulatorLoop(MakeChannelElement(NewTime(0), tokenValue(100)))
+		if _, err := Simulate(graph, WithBackend(backend)); err != nil {
+			t.Fatalf("%v: %v", backend, err)
+		}
+		expected := []int64{101, 103, 106, 110}
+		if len(*received) != len(expected) {
+			t.Fatalf("%v: expected %v, got %v", backend, expected, *received)
+		}
+		for i := range expected {
+			if (*received)[i] != expected[i] {
+				t.Fatalf("%v: expected %v, got %v", backend, expected, *received)
+			}
+		}
+	}
+}
+
+func TestCycleWithoutTokens(t *testing.T) {
+	graph, _ := makeAccumulatorLoop()
+	_, err := Simulate(graph)
+	if err == nil {
+		t.Fatal("Expected the loop without tokens to be rejected")
+	}
+	if !strings.Contains(err.Error(), "has no channel with initial tokens") {
+		t.Errorf("Expected the cycle to be reported, got %v", err)
+	}
+}
+
+func TestInitialTokensTakeCapacity(t *testing.T) {
+	channel := MakeCommunicationChannel[datatypes.Bit](2, WithInitialTokens(MakeChannelElement(NewTime(0), datatypes.Bit{})))
+	var fullAfter int
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for !node.OutputChannel(0).IsFull() {
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+			fullAfter++
+		}
+	}, (*any)(nil))
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.AdvanceToTime(NewTime(10))
+	}, (*any)(nil))
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx := MakePrimitiveContext(nil)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+	if _, err := Simulate(ctx, WithBackend(DeterministicBackend)); err != nil {
+		t.Fatal(err)
+	}
+	if fullAfter != 1 {
+		t.Errorf("Expected the token to leave room for just one element, but %d were sent", fullAfter)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected too many tokens to panic")
+		}
+	}()
+	MakeCommunicationChannel[datatypes.Bit](1).SetInitialTokens(make([]ChannelElement, 2)...)
+}
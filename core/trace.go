@@ -0,0 +1,203 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// A ChromeTracer records a timeline of the simulation, which can be written in the Chrome Trace Event format
+// and opened in chrome://tracing or Perfetto. Each context gets its own track, showing what it spent its time on,
+// along with instants for each element it sent or received.
+type ChromeTracer struct {
+	// How many units of Time make up a microsecond in the timeline.
+	// The default shows each cycle as a microsecond. With clock domains the base unit is a femtosecond,
+	// so set it to 1e9 for the timeline to be in real time.
+	TimePerMicrosecond float64
+
+	mutex  sync.Mutex
+	tracks []*traceTrack
+	byCtx  map[ContextView]*traceTrack
+}
+
+func MakeChromeTracer() *ChromeTracer {
+	return &ChromeTracer{
+		TimePerMicrosecond: 1,
+		byCtx:              map[ContextView]*traceTrack{},
+	}
+}
+
+// WithTracer records the simulation to tracer.
+func WithTracer(tracer *ChromeTracer) SimulationOption {
+	return func(conf *simulationConfig) {
+		conf.tracer = tracer
+	}
+}
+
+type traceEvent struct {
+	name, category string
+	// 'X' for a span from start to end, and 'i' for an instant at start
+	phase      byte
+	start, end Time
+	args       map[string]string
+}
+
+type traceTrack struct {
+	name string
+
+	mutex  sync.Mutex
+	events []traceEvent
+	// The span that the next advance may extend, if it's for the same activity.
+	open *traceEvent
+}
+
+// track returns the track for ctx, creating it if needed. The tracer may be nil.
+func (tracer *ChromeTracer) track(ctx ContextView) *traceTrack {
+	if tracer == nil || ctx == nil {
+		return nil
+	}
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	if track, ok := tracer.byCtx[ctx]; ok {
+		return track
+	}
+	track := &traceTrack{name: viewToString(ctx)}
+	tracer.byCtx[ctx] = track
+	tracer.tracks = append(tracer.tracks, track)
+	return track
+}
+
+// A trackBinder records the time that it advances onto its track.
+type trackBinder interface {
+	bindTrack(*traceTrack)
+}
+
+func (prim *TickTime) bindTrack(track *traceTrack) {
+	prim.track = track
+}
+
+// advance records that the time from start to end was spent on activity. The track may be nil.
+func (track *traceTrack) advance(start, end *Time, activity Activity) {
+	if track == nil || end.IsInf() || start.Cmp(end) == 0 {
+		return
+	}
+	track.mutex.Lock()
+	defer track.mutex.Unlock()
+	name := activity.String()
+	if track.open != nil && track.open.name == name && track.open.end.Cmp(start) == 0 {
+		track.open.end.Set(end)
+		return
+	}
+	track.flush()
+	track.open = &traceEvent{name: name, category: "activity", phase: 'X'}
+	track.open.start.Set(start)
+	track.open.end.Set(end)
+}
+
+// flush closes the open span. The caller must hold the mutex.
+func (track *traceTrack) flush() {
+	if track.open != nil {
+		track.events = append(track.events, *track.open)
+		track.open = nil
+	}
+}
+
+// instant records an event at time, with args given as alternating keys and values. The track may be nil.
+func (track *traceTrack) instant(category, name string, time *Time, args ...any) {
+	if track == nil || time.IsInf() {
+		return
+	}
+	event := traceEvent{name: name, category: category, phase: 'i', args: map[string]string{}}
+	event.start.Set(time)
+	for i := 0; i+1 < len(args); i += 2 {
+		event.args[fmt.Sprint(args[i])] = fmt.Sprint(args[i+1])
+	}
+	track.mutex.Lock()
+	defer track.mutex.Unlock()
+	track.events = append(track.events, event)
+}
+
+// traceChannel records an instant on the track of ctx for an element going through cchan.
+func (cchan *CommunicationChannel) traceChannel(ctx ContextView, name string, time *Time, ce *ChannelElement) {
+	cchan.tracer.track(ctx).instant("channel", name, time, "channel", cchan.endpointString(), "time", &ce.Time, "data", ce.Data)
+}
+
+// TraceEvent records an instant on the track of ctx, if the simulation it belongs to is being traced.
+// args are alternating keys and values, which are only formatted if the event is recorded.
+func TraceEvent(ctx ContextView, category, name string, time *Time, args ...any) {
+	simulationOf(ctx).tracer().track(ctx).instant(category, name, time, args...)
+}
+
+func (sim *simulation) tracer() *ChromeTracer {
+	if sim == nil {
+		return nil
+	}
+	return sim.chromeTracer
+}
+
+// traceContext gives ctx its own track, if the simulation is being traced.
+func (sim *simulation) traceContext(ctx Context) {
+	track := sim.tracer().track(ctx)
+	if binder, ok := ctx.(trackBinder); ok && track != nil {
+		binder.bindTrack(track)
+	}
+}
+
+type chromeEvent struct {
+	Name     string            `json:"name"`
+	Category string            `json:"cat,omitempty"`
+	Phase    string            `json:"ph"`
+	Time     float64           `json:"ts"`
+	Duration *float64          `json:"dur,omitempty"`
+	Scope    string            `json:"s,omitempty"`
+	Pid      int               `json:"pid"`
+	Tid      int               `json:"tid"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+func (tracer *ChromeTracer) microseconds(t *Time) float64 {
+	time, _ := new(big.Float).SetInt(t.bigInt()).Float64()
+	return time / tracer.TimePerMicrosecond
+}
+
+// WriteJSON writes everything recorded so far in the Chrome Trace Event format.
+func (tracer *ChromeTracer) WriteJSON(w io.Writer) error {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	events := []chromeEvent{}
+	for tid, track := range tracer.tracks {
+		events = append(events, chromeEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			Tid:   tid,
+			Args:  map[string]string{"name": track.name},
+		})
+		track.mutex.Lock()
+		track.flush()
+		for _, event := range track.events {
+			converted := chromeEvent{
+				Name:     event.name,
+				Category: event.category,
+				Phase:    string(event.phase),
+				Time:     tracer.microseconds(&event.start),
+				Tid:      tid,
+				Args:     event.args,
+			}
+			if event.phase == 'X' {
+				duration := tracer.microseconds(new(Time).Sub(&event.end, &event.start))
+				converted.Duration = &duration
+			} else {
+				// Instants only mark their own track.
+				converted.Scope = "t"
+			}
+			events = append(events, converted)
+		}
+		track.mutex.Unlock()
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []chromeEvent `json:"traceEvents"`
+		DisplayTimeUnit string        `json:"displayTimeUnit"`
+	}{events, "ns"})
+}
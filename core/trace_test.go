@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+type decodedTrace struct {
+	TraceEvents []struct {
+		Name     string            `json:"name"`
+		Category string            `json:"cat"`
+		Phase    string            `json:"ph"`
+		Time     float64           `json:"ts"`
+		Duration float64           `json:"dur"`
+		Tid      int               `json:"tid"`
+		Args     map[string]string `json:"args"`
+	} `json:"traceEvents"`
+}
+
+func TestChromeTracer(t *testing.T) {
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Bit](4)
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.IncrCycles(NewTime(3))
+		node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{Value: true}))
+	}, (*any)(nil))
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		DequeueInputChansByID(node, 0)
+		node.IncrCycles(NewTime(2))
+	}, (*any)(nil))
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+
+	tracer := MakeChromeTracer()
+	if _, err := Simulate(ctx, WithBackend(DeterministicBackend), WithTracer(tracer)); err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := tracer.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var trace decodedTrace
+	if err := json.Unmarshal(buffer.Bytes(), &trace); err != nil {
+		t.Fatalf("Expected valid JSON: %v\n%s", err, buffer.String())
+	}
+
+	tracks := map[int]string{}
+	seen := map[string]bool{}
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "M" {
+			tracks[event.Tid] = event.Args["name"]
+			continue
+		}
+		key := tracks[event.Tid] + ":" + event.Phase + ":" + event.Name
+		seen[key] = true
+		t.Logf("%s at %v for %v %v", key, event.Time, event.Duration, event.Args)
+		switch key {
+		case CtxToString(producer) + ":i:enqueue":
+			if event.Time != 3 || event.Args["data"] != "{true}" {
+				t.Errorf("Expected the enqueue of true at 3, got %+v", event)
+			}
+		case CtxToString(consumer) + ":X:Starved":
+			if event.Time != 0 || event.Duration != 3 {
+				t.Errorf("Expected the consumer to starve from 0 to 3, got %+v", event)
+			}
+		case CtxToString(consumer) + ":X:Compute":
+			if event.Time != 3 || event.Duration != 2 {
+				t.Errorf("Expected the consumer to compute from 3 to 5, got %+v", event)
+			}
+		}
+	}
+	for _, key := range []string{
+		CtxToString(producer) + ":X:Compute",
+		CtxToString(producer) + ":i:enqueue",
+		CtxToString(consumer) + ":X:Starved",
+		CtxToString(consumer) + ":i:dequeue",
+		CtxToString(consumer) + ":X:Compute",
+	} {
+		if !seen[key] {
+			t.Errorf("Expected an event for %s", key)
+		}
+	}
+}
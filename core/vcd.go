@@ -0,0 +1,280 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// A VCDTracer records the traffic through a set of channels, and writes it out as a VCD waveform.
+// Each channel becomes a scope with a valid signal (it holds an element), a ready signal (it isn't full),
+// and the bits of the element at its head.
+type VCDTracer struct {
+	// The unit of Time in the waveform. The default shows each cycle as a nanosecond.
+	// With clock domains the base unit is a femtosecond, so set it to "1fs" for the waveform to be in real time.
+	Timescale string
+
+	mutex    sync.Mutex
+	channels []*channelTrace
+}
+
+type channelTrace struct {
+	name     string
+	capacity int
+
+	mutex    sync.Mutex
+	events   []occupancyEvent
+	elements []datatypes.DAMType
+}
+
+func MakeVCDTracer() *VCDTracer {
+	return &VCDTracer{Timescale: "1ns"}
+}
+
+// Trace starts recording each of channels, named after their endpoints.
+// It should be called before the simulation starts.
+func (tracer *VCDTracer) Trace(channels ...*CommunicationChannel) *VCDTracer {
+	for _, channel := range channels {
+		tracer.TraceNamed(channel.endpointString(), channel)
+	}
+	return tracer
+}
+
+// TraceNamed starts recording channel under the given name.
+func (tracer *VCDTracer) TraceNamed(name string, channel *CommunicationChannel) *VCDTracer {
+	if channel.trace != nil {
+		panic(fmt.Sprintf("Channel %s is already being traced as %s", name, channel.trace.name))
+	}
+	channel.trace = &channelTrace{name: name, capacity: channel.slots()}
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	tracer.channels = append(tracer.channels, channel.trace)
+	return tracer
+}
+
+// The record methods are no-ops if the channel isn't traced.
+
+func (trace *channelTrace) recordEnqueue(ce *ChannelElement) {
+	if trace == nil {
+		return
+	}
+	trace.mutex.Lock()
+	defer trace.mutex.Unlock()
+	event := occupancyEvent{delta: 1}
+	event.time.Set(&ce.Time)
+	trace.events = append(trace.events, event)
+	trace.elements = append(trace.elements, ce.Data)
+}
+
+func (trace *channelTrace) recordDequeue(ce *ChannelElement) {
+	if trace == nil {
+		return
+	}
+	trace.mutex.Lock()
+	defer trace.mutex.Unlock()
+	event := occupancyEvent{delta: -1}
+	event.time.Set(&ce.Time)
+	trace.events = append(trace.events, event)
+}
+
+// A vcdField is one data signal, holding some of the bits of an element.
+type vcdField struct {
+	name  string
+	width uint
+	value *big.Int
+}
+
+// vcdFields flattens data into signals. Types other than Bit, FixedPoint, and vectors of them have no data signals.
+func vcdFields(name string, data datatypes.DAMType) []vcdField {
+	switch value := data.(type) {
+	case datatypes.Bit:
+		bit := big.NewInt(0)
+		if value.Value {
+			bit.SetInt64(1)
+		}
+		return []vcdField{{name: name, width: 1, value: bit}}
+	case datatypes.FixedPoint:
+		if value.Tp.NBits() == 0 {
+			return nil
+		}
+		// Negative values are already held as their two's complement bits.
+		return []vcdField{{name: name, width: value.Tp.NBits(), value: &value.Underlying}}
+	case interface{ Lanes() []datatypes.DAMType }:
+		var fields []vcdField
+		for i, lane := range value.Lanes() {
+			fields = append(fields, vcdFields(fmt.Sprintf("%s_%d", name, i), lane)...)
+		}
+		return fields
+	}
+	return nil
+}
+
+// A vcdSignal is a declared variable, along with the last value written for it.
+type vcdSignal struct {
+	id    string
+	width uint
+	last  string
+}
+
+func (signal *vcdSignal) format(value string) string {
+	if signal.width == 1 {
+		return value + signal.id
+	}
+	return "b" + value + " " + signal.id
+}
+
+// vcdIdentifier turns n into a short code made of printable characters.
+func vcdIdentifier(n int) string {
+	const first, count = '!', '~' - '!' + 1
+	id := []byte{byte(first + n%count)}
+	for n /= count; n > 0; n /= count {
+		id = append(id, byte(first+n%count))
+	}
+	return string(id)
+}
+
+// vcdScopeName makes name usable as a VCD scope.
+func vcdScopeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+type tracedChannel struct {
+	*channelTrace
+	valid, ready *vcdSignal
+	data         map[string]*vcdSignal
+	dataOrder    []string
+
+	occupancy, dequeued int
+}
+
+// update brings the signals of the channel up to date, writing any that changed.
+func (channel *tracedChannel) update(w io.Writer) {
+	set := func(signal *vcdSignal, value string) {
+		if signal.last != value {
+			signal.last = value
+			fmt.Fprintln(w, signal.format(value))
+		}
+	}
+	set(channel.valid, boolBit(channel.occupancy > 0))
+	set(channel.ready, boolBit(channel.occupancy < channel.capacity))
+	values := map[string]string{}
+	if channel.occupancy > 0 && channel.dequeued < len(channel.elements) {
+		for _, field := range vcdFields("data", channel.elements[channel.dequeued]) {
+			values[field.name] = field.value.Text(2)
+		}
+	}
+	for _, name := range channel.dataOrder {
+		value, ok := values[name]
+		if !ok {
+			value = "x"
+		}
+		set(channel.data[name], value)
+	}
+}
+
+func boolBit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// WriteVCD writes the traffic recorded so far. Elements become valid at their Time, and stay at the head of the
+// channel until they are dequeued.
+func (tracer *VCDTracer) WriteVCD(w io.Writer) error {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "$version DAM $end")
+	fmt.Fprintf(out, "$timescale %s $end\n", tracer.Timescale)
+	fmt.Fprintln(out, "$scope module dam $end")
+
+	type timedEvent struct {
+		occupancyEvent
+		channel *tracedChannel
+	}
+	var events []timedEvent
+	var channels []*tracedChannel
+	signals := 0
+	declare := func(name string, width uint) *vcdSignal {
+		signal := &vcdSignal{id: vcdIdentifier(signals), width: width}
+		signals++
+		fmt.Fprintf(out, "$var wire %d %s %s $end\n", width, signal.id, name)
+		return signal
+	}
+	for _, trace := range tracer.channels {
+		trace.mutex.Lock()
+		channel := &tracedChannel{channelTrace: trace, data: map[string]*vcdSignal{}}
+		fmt.Fprintf(out, "$scope module %s $end\n", vcdScopeName(trace.name))
+		channel.valid = declare("valid", 1)
+		channel.ready = declare("ready", 1)
+		// Declare a signal for every field seen, in case elements differ in shape.
+		for _, element := range trace.elements {
+			for _, field := range vcdFields("data", element) {
+				if _, ok := channel.data[field.name]; !ok {
+					channel.data[field.name] = declare(field.name, field.width)
+					channel.dataOrder = append(channel.dataOrder, field.name)
+				}
+			}
+		}
+		fmt.Fprintln(out, "$upscope $end")
+		for _, event := range trace.events {
+			events = append(events, timedEvent{event, channel})
+		}
+		channels = append(channels, channel)
+		trace.mutex.Unlock()
+	}
+	fmt.Fprintln(out, "$upscope $end")
+	fmt.Fprintln(out, "$enddefinitions $end")
+
+	fmt.Fprintln(out, "#0")
+	fmt.Fprintln(out, "$dumpvars")
+	for _, channel := range channels {
+		channel.update(out)
+	}
+	fmt.Fprintln(out, "$end")
+
+	// Enqueues and dequeues are recorded by different contexts, so put them back in time order.
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].time.Cmp(&events[j].time) < 0
+	})
+	last := NewTime(0)
+	for start := 0; start < len(events); {
+		now := &events[start].time
+		if now.IsInf() {
+			break
+		}
+		touched := map[*tracedChannel]bool{}
+		end := start
+		for ; end < len(events) && events[end].time.Cmp(now) == 0; end++ {
+			channel := events[end].channel
+			channel.occupancy += events[end].delta
+			if events[end].delta < 0 {
+				channel.dequeued++
+			}
+			touched[channel] = true
+		}
+		if now.Cmp(last) != 0 {
+			fmt.Fprintf(out, "#%v\n", now)
+			last.Set(now)
+		}
+		for _, channel := range channels {
+			if touched[channel] {
+				channel.update(out)
+			}
+		}
+		start = end
+	}
+	return out.Flush()
+}
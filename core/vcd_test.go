@@ -0,0 +1,86 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+func TestVCDTracer(t *testing.T) {
+	fpt := datatypes.FixedPointType{Signed: true, Integer: 4, Fraction: 0}
+	makeVector := func(a, b int64) datatypes.Vector[datatypes.FixedPoint] {
+		vector := datatypes.NewVector[datatypes.FixedPoint](2)
+		for i, value := range []int64{a, b} {
+			lane := datatypes.FixedPoint{Tp: fpt}
+			lane.SetInt64(value)
+			vector.Set(i, lane)
+		}
+		return vector
+	}
+	ctx := MakePrimitiveContext(nil)
+	channel := MakeCommunicationChannel[datatypes.Vector[datatypes.FixedPoint]](2)
+	tracer := MakeVCDTracer().TraceNamed("vectors", channel)
+
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), makeVector(3, 5)))
+		node.IncrCycles(OneTick)
+		node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), makeVector(-1, 0)))
+	}, (*any)(nil))
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.IncrCycles(NewTime(5))
+		for i := 0; i < 2; i++ {
+			DequeueInputChansByID(node, 0)
+			node.IncrCycles(OneTick)
+		}
+	}, (*any)(nil))
+	producer.AddOutputChannel(channel)
+	consumer.AddInputChannel(channel)
+	ctx.AddChild(producer)
+	ctx.AddChild(consumer)
+	if _, err := Simulate(ctx, WithBackend(DeterministicBackend)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := tracer.WriteVCD(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + buffer.String())
+	// Without clock domains, each tick is a cycle.
+	if !strings.Contains(buffer.String(), "$timescale 1ns $end") {
+		t.Errorf("Expected a timescale of 1ns")
+	}
+	// valid is !, ready is ", and the two lanes are # and $.
+	expected := strings.Join([]string{
+		"$var wire 4 # data_0 $end",
+		"$var wire 4 $ data_1 $end",
+		"$upscope $end",
+		"$upscope $end",
+		"$enddefinitions $end",
+		"#0",
+		"$dumpvars",
+		"0!",
+		"1\"",
+		"bx #",
+		"bx $",
+		"$end",
+		"1!",
+		"b11 #",
+		"b101 $",
+		"#1",
+		"0\"",
+		"#5",
+		"1\"",
+		"b1111 #",
+		"b0 $",
+		"#6",
+		"0!",
+		"bx #",
+		"bx $",
+	}, "\n")
+	if !strings.Contains(buffer.String(), expected) {
+		t.Errorf("Expected the waveform to contain:\n%s", expected)
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/datatypes"
+)
+
+// Sends an element at each of the send times, then runs consume against them.
+func runWindow(t *testing.T, backend Backend, sendTimes []int64, consume func(node *SimpleNode[any])) {
+	channel := MakeCommunicationChannel[datatypes.Bit](len(sendTimes))
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for _, sendTime := range sendTimes {
+			node.AdvanceToTime(NewTime(sendTime))
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+		}
+	}, (*any)(nil))
+	runPair(t, backend, channel, producer, MakeSimpleNode(consume, (*any)(nil)))
+}
+
+func elementTimes(elements []ChannelElement) []int64 {
+	result := []int64{}
+	for _, element := range elements {
+		time := element.Time.GetTime()
+		result = append(result, time.Int64())
+	}
+	return result
+}
+
+func checkWindow(t *testing.T, backend Backend, what string, expectedTimes []int64, expectedStatus Status, elements []ChannelElement, status Status) {
+	t.Helper()
+	if got := elementTimes(elements); !reflect.DeepEqual(expectedTimes, got) || status != expectedStatus {
+		t.Errorf("%v: expected %s to give %v (%v), got %v (%v)", backend, what, expectedTimes, expectedStatus, got, status)
+	}
+}
+
+func TestPeekN(t *testing.T) {
+	for _, backend := range backends {
+		runWindow(t, backend, []int64{0, 1, 2, 3, 4}, func(node *SimpleNode[any]) {
+			node.AdvanceToTime(NewTime(2))
+			elements, status := node.InputChannel(0).PeekN(4)
+			checkWindow(t, backend, "PeekN(4) at 2", []int64{0, 1, 2}, Nothing, elements, status)
+			node.AdvanceToTime(NewTime(10))
+			elements, status = node.InputChannel(0).PeekN(4)
+			checkWindow(t, backend, "PeekN(4) at 10", []int64{0, 1, 2, 3}, Ok, elements, status)
+			elements, status = node.InputChannel(0).PeekN(10)
+			checkWindow(t, backend, "PeekN(10) at 10", []int64{0, 1, 2, 3, 4}, Closed, elements, status)
+			// Peeking doesn't take anything.
+			if ce, status := node.InputChannel(0).Dequeue(); status != Ok || ce.Time.Cmp(NewTime(10)) != 0 {
+				t.Errorf("%v: expected to still dequeue the first element, got %v (%v)", backend, ce, status)
+			}
+		})
+	}
+}
+
+func TestDequeueUntil(t *testing.T) {
+	for _, backend := range backends {
+		runWindow(t, backend, []int64{0, 2, 4, 6, 8}, func(node *SimpleNode[any]) {
+			elements, status := node.InputChannel(0).DequeueUntil(NewTime(5))
+			checkWindow(t, backend, "DequeueUntil(5)", []int64{0, 2, 4}, Ok, elements, status)
+			elements, status = node.InputChannel(0).DequeueUntil(NewTime(5))
+			checkWindow(t, backend, "DequeueUntil(5) again", []int64{}, Nothing, elements, status)
+			node.AdvanceToTime(NewTime(7))
+			elements, status = node.InputChannel(0).DequeueUntil(NewTime(100))
+			// Elements which arrived before the consumer's time are dequeued at its time.
+			checkWindow(t, backend, "DequeueUntil(100)", []int64{7, 8}, Closed, elements, status)
+		})
+	}
+}
+
+// Elements which have been peeked at are still in the channel, so they keep taking up its capacity.
+func TestPeekNKeepsOccupancy(t *testing.T) {
+	channel := MakeCommunicationChannel[datatypes.Bit](2)
+	var blockedUntil *Time
+	producer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		for i := 0; i < 3; i++ {
+			AdvanceUntilCanEnqueue(node, 0)
+			node.OutputChannel(0).Enqueue(MakeChannelElement(node.TickLowerBound(), datatypes.Bit{}))
+		}
+		blockedUntil = new(Time).Set(node.TickLowerBound())
+	}, (*any)(nil))
+	consumer := MakeSimpleNode(func(node *SimpleNode[any]) {
+		node.AdvanceToTime(NewTime(1))
+		if elements, _ := node.InputChannel(0).PeekN(2); len(elements) != 2 {
+			t.Errorf("Expected to see 2 elements, got %d", len(elements))
+		}
+		node.AdvanceToTime(NewTime(20))
+		node.InputChannel(0).DequeueUntil(NewTime(20))
+	}, (*any)(nil))
+	runPair(t, DeterministicBackend, channel, producer, consumer)
+	if blockedUntil.Cmp(NewTime(20)) != 0 {
+		t.Errorf("Expected the producer to wait until the peeked elements were dequeued at 20, got %v", blockedUntil)
+	}
+}
@@ -1,6 +1,8 @@
 package datatypes
 
 import (
+	"bytes"
+	"encoding/gob"
 	"math"
 	"math/big"
 	"testing"
@@ -125,3 +127,30 @@ func TestFixedPointMul(t *testing.T) {
 		t.Errorf("Expected -3.5 * 5 = -17.5, got %s", val3.ToFloat().String())
 	}
 }
+
+func TestGobRoundTrip(t *testing.T) {
+	fpt := FixedPointType{true, 4, 4}
+	v := NewVector[FixedPoint](2)
+	for i, value := range []int64{-3, 5} {
+		fp := FixedPoint{Tp: fpt}
+		fp.SetInt64(value)
+		v.Set(i, fp)
+	}
+	var buffer bytes.Buffer
+	// Going through an interface is how values are held on channels.
+	var encoded DAMType = v
+	gob.Register(v)
+	if err := gob.NewEncoder(&buffer).Encode(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	var decoded DAMType
+	if err := gob.NewDecoder(&buffer).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	result := decoded.(Vector[FixedPoint])
+	for i := 0; i < v.Width(); i++ {
+		if Cmp(result.Get(i), v.Get(i)) != 0 || result.Get(i).Tp != fpt {
+			t.Errorf("Lane %d changed from %s to %s", i, v.Get(i), result.Get(i))
+		}
+	}
+}
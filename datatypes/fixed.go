@@ -1,6 +1,8 @@
 package datatypes
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -82,6 +84,31 @@ func (fp FixedPoint) Payload() any {
 	return fp
 }
 
+type encodedFixedPoint struct {
+	Tp         FixedPointType
+	Underlying []byte
+}
+
+// GobEncode lets a FixedPoint be saved even when it's held in an interface, where its big.Int isn't addressable.
+func (fp FixedPoint) GobEncode() ([]byte, error) {
+	underlying, err := fp.Underlying.GobEncode()
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err = gob.NewEncoder(&buffer).Encode(encodedFixedPoint{fp.Tp, underlying})
+	return buffer.Bytes(), err
+}
+
+func (fp *FixedPoint) GobDecode(data []byte) error {
+	var encoded encodedFixedPoint
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&encoded); err != nil {
+		return err
+	}
+	fp.Tp = encoded.Tp
+	return fp.Underlying.GobDecode(encoded.Underlying)
+}
+
 func (fp *FixedPoint) NegInPlace() {
 	if !fp.Tp.Signed {
 		panic("Cannot negate an unsigned number!")
@@ -1,6 +1,10 @@
 package datatypes
 
-import "math/big"
+import (
+	"bytes"
+	"encoding/gob"
+	"math/big"
+)
 
 // TODO:  Does golang support another generic param to set length of array
 // that way, we can embed a static-sized array in the struct itself without having
@@ -36,6 +40,26 @@ func (v *Vector[T]) Get(index int) T {
 	return v.data[index]
 }
 
+// Lanes returns the elements of the vector, for code which can't name T.
+func (v Vector[T]) Lanes() []DAMType {
+	lanes := make([]DAMType, len(v.data))
+	for i, lane := range v.data {
+		lanes[i] = lane
+	}
+	return lanes
+}
+
+// GobEncode lets vectors be saved, since their elements are unexported.
+func (v Vector[T]) GobEncode() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(v.data)
+	return buffer.Bytes(), err
+}
+
+func (v *Vector[T]) GobDecode(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(&v.data)
+}
+
 func (v Vector[T]) Validate() bool {
 	for _, v := range v.data {
 		if !v.Validate() {
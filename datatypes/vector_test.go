@@ -34,3 +34,12 @@ func TestGetAndSet(t *testing.T) {
 		t.Logf("Pass")
 	}
 }
+
+func TestLanes(t *testing.T) {
+	v := NewVector[Bit](3)
+	v.Set(1, Bit{Value: true})
+	lanes := v.Lanes()
+	if len(lanes) != 3 || lanes[1].(Bit) != (Bit{Value: true}) || lanes[2].(Bit) != (Bit{}) {
+		t.Errorf("Fail: Lanes should match the vector's elements, got %v", lanes)
+	}
+}
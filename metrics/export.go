@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A Format is a way of writing out a Registry.
+type Format uint8
+
+const (
+	CSV Format = iota
+	JSON
+	// The Prometheus text exposition format
+	Prometheus
+)
+
+func (format Format) String() string {
+	switch format {
+	case CSV:
+		return "CSV"
+	case JSON:
+		return "JSON"
+	case Prometheus:
+		return "Prometheus"
+	}
+	return "X"
+}
+
+// Write writes every metric in the given format.
+func (registry *Registry) Write(w io.Writer, format Format) error {
+	switch format {
+	case CSV:
+		return registry.WriteCSV(w)
+	case JSON:
+		return registry.WriteJSON(w)
+	case Prometheus:
+		return registry.WritePrometheus(w)
+	}
+	return fmt.Errorf("Unknown metrics format %v", format)
+}
+
+// WriteFile writes every metric to the file at path, replacing it if it exists.
+func (registry *Registry) WriteFile(path string, format Format) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := registry.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type entry struct {
+	key
+	metric metric
+}
+
+// entries returns the metrics in the order they were registered.
+func (registry *Registry) entries() []entry {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	entries := make([]entry, len(registry.order))
+	for i, k := range registry.order {
+		entries[i] = entry{k, registry.metrics[k]}
+	}
+	return entries
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// A field is a single number describing a metric, such as one of the buckets of a histogram.
+type field struct {
+	name  string
+	value string
+}
+
+func fields(metric metric) []field {
+	switch m := metric.(type) {
+	case *Counter:
+		return []field{{"value", strconv.FormatInt(m.Value(), 10)}}
+	case *Gauge:
+		snapshot := m.Snapshot()
+		return []field{
+			{"value", formatFloat(snapshot.Value)},
+			{"min", formatFloat(snapshot.Min)},
+			{"max", formatFloat(snapshot.Max)},
+			{"time_weighted_mean", formatFloat(snapshot.TimeWeightedMean)},
+		}
+	case *Histogram:
+		snapshot := m.Value()
+		result := []field{{"count", strconv.FormatUint(snapshot.Count, 10)}, {"sum", formatFloat(snapshot.Sum)}}
+		// Buckets are cumulative, as in Prometheus.
+		cumulative := uint64(0)
+		for i, bound := range snapshot.Bounds {
+			cumulative += snapshot.Counts[i]
+			result = append(result, field{"le_" + formatFloat(bound), strconv.FormatUint(cumulative, 10)})
+		}
+		return result
+	}
+	return nil
+}
+
+// WriteCSV writes one row for each number describing each metric.
+func (registry *Registry) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"context", "name", "type", "field", "value"}); err != nil {
+		return err
+	}
+	for _, entry := range registry.entries() {
+		for _, field := range fields(entry.metric) {
+			if err := writer.Write([]string{entry.context, entry.name, entry.metric.kind(), field.name, field.value}); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type jsonBucket struct {
+	// Bounds are strings, since the last one is infinite.
+	UpperBound string `json:"le"`
+	Count      uint64 `json:"count"`
+}
+
+type jsonSample struct {
+	Time  string  `json:"time"`
+	Value float64 `json:"value"`
+}
+
+type jsonMetric struct {
+	Context string `json:"context"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+
+	Value            any          `json:"value,omitempty"`
+	Min              *float64     `json:"min,omitempty"`
+	Max              *float64     `json:"max,omitempty"`
+	TimeWeightedMean *float64     `json:"time_weighted_mean,omitempty"`
+	Count            *uint64      `json:"count,omitempty"`
+	Sum              *float64     `json:"sum,omitempty"`
+	Buckets          []jsonBucket `json:"buckets,omitempty"`
+	Samples          []jsonSample `json:"samples,omitempty"`
+}
+
+// WriteJSON writes a list of every metric. Histograms include each of their samples.
+func (registry *Registry) WriteJSON(w io.Writer) error {
+	result := []jsonMetric{}
+	for _, entry := range registry.entries() {
+		converted := jsonMetric{Context: entry.context, Name: entry.name, Type: entry.metric.kind()}
+		switch m := entry.metric.(type) {
+		case *Counter:
+			converted.Value = m.Value()
+		case *Gauge:
+			snapshot := m.Snapshot()
+			converted.Value = snapshot.Value
+			converted.Min, converted.Max, converted.TimeWeightedMean = &snapshot.Min, &snapshot.Max, &snapshot.TimeWeightedMean
+		case *Histogram:
+			snapshot := m.Value()
+			converted.Count, converted.Sum = &snapshot.Count, &snapshot.Sum
+			for i, bound := range snapshot.Bounds {
+				converted.Buckets = append(converted.Buckets, jsonBucket{formatFloat(bound), snapshot.Counts[i]})
+			}
+			for _, sample := range snapshot.Samples {
+				converted.Samples = append(converted.Samples, jsonSample{sample.Time.String(), sample.Value})
+			}
+		}
+		result = append(result, converted)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// prometheusName turns name into a valid Prometheus metric name.
+func prometheusName(name string) string {
+	result := strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if result == "" || ('0' <= result[0] && result[0] <= '9') {
+		result = "_" + result
+	}
+	return result
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// A family is every sample of a single Prometheus metric, across contexts.
+type family struct {
+	kind  string
+	lines []string
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format, with the context as a label.
+// Gauges also get a <name>_time_weighted_mean gauge.
+func (registry *Registry) WritePrometheus(w io.Writer) error {
+	families := map[string]*family{}
+	var order []string
+	add := func(familyName, kind, sampleName, labels, value string) {
+		f, ok := families[familyName]
+		if !ok {
+			f = &family{kind: kind}
+			families[familyName] = f
+			order = append(order, familyName)
+		}
+		f.lines = append(f.lines, fmt.Sprintf("%s{%s} %s", sampleName, labels, value))
+	}
+	for _, entry := range registry.entries() {
+		name := prometheusName(entry.name)
+		labels := fmt.Sprintf(`context="%s"`, labelEscaper.Replace(entry.context))
+		switch m := entry.metric.(type) {
+		case *Counter:
+			add(name, "counter", name, labels, strconv.FormatInt(m.Value(), 10))
+		case *Gauge:
+			snapshot := m.Snapshot()
+			add(name, "gauge", name, labels, formatFloat(snapshot.Value))
+			mean := name + "_time_weighted_mean"
+			add(mean, "gauge", mean, labels, formatFloat(snapshot.TimeWeightedMean))
+		case *Histogram:
+			snapshot := m.Value()
+			cumulative := uint64(0)
+			for i, bound := range snapshot.Bounds {
+				cumulative += snapshot.Counts[i]
+				add(name, "histogram", name+"_bucket", fmt.Sprintf(`%s,le="%s"`, labels, formatFloat(bound)), strconv.FormatUint(cumulative, 10))
+			}
+			add(name, "histogram", name+"_sum", labels, formatFloat(snapshot.Sum))
+			add(name, "histogram", name+"_count", labels, strconv.FormatUint(snapshot.Count, 10))
+		}
+	}
+	for _, name := range order {
+		f := families[name]
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind); err != nil {
+			return err
+		}
+		for _, line := range f.lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,3 +1,240 @@
 package metrics
 
-type MetricType[T any] interface{}
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stanford-ppl/DAM/core"
+)
+
+// A MetricType is a metric whose current value is of type T.
+type MetricType[T any] interface {
+	Value() T
+}
+
+var (
+	_ MetricType[int64]             = (*Counter)(nil)
+	_ MetricType[float64]           = (*Gauge)(nil)
+	_ MetricType[HistogramSnapshot] = (*Histogram)(nil)
+)
+
+// A metric is anything that can be held by a Registry.
+type metric interface {
+	kind() string
+}
+
+type key struct {
+	context, name string
+}
+
+// A Registry holds metrics keyed by the context which emits them and their name.
+// Nodes should look their metrics up once, and then update them directly, which is cheap.
+type Registry struct {
+	mutex   sync.Mutex
+	metrics map[key]metric
+	// Keys in the order they were registered
+	order []key
+}
+
+func MakeRegistry() *Registry {
+	return &Registry{metrics: map[key]metric{}}
+}
+
+// contextName names ctx with core.CtxToString. A nil ctx is for metrics that don't belong to any context.
+func contextName(ctx core.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	return core.CtxToString(ctx)
+}
+
+// lookup returns the metric registered under ctx and name, making it if needed.
+func lookup[M metric](registry *Registry, ctx core.Context, name string, create func() M) M {
+	k := key{contextName(ctx), name}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if existing, ok := registry.metrics[k]; ok {
+		typed, ok := existing.(M)
+		if !ok {
+			panic(fmt.Sprintf("Metric %s of %s is a %s, not a %s", name, k.context, existing.kind(), create().kind()))
+		}
+		return typed
+	}
+	created := create()
+	registry.metrics[k] = created
+	registry.order = append(registry.order, k)
+	return created
+}
+
+// Counter returns the counter named name for ctx.
+func (registry *Registry) Counter(ctx core.Context, name string) *Counter {
+	return lookup(registry, ctx, name, func() *Counter { return new(Counter) })
+}
+
+// Gauge returns the gauge named name for ctx.
+func (registry *Registry) Gauge(ctx core.Context, name string) *Gauge {
+	return lookup(registry, ctx, name, func() *Gauge { return new(Gauge) })
+}
+
+// Histogram returns the histogram named name for ctx. bounds are the upper bounds of its buckets, and are only
+// used the first time the histogram is looked up.
+func (registry *Registry) Histogram(ctx core.Context, name string, bounds ...float64) *Histogram {
+	return lookup(registry, ctx, name, func() *Histogram { return makeHistogram(bounds) })
+}
+
+// A Counter only goes up.
+type Counter struct {
+	value atomic.Int64
+}
+
+func (*Counter) kind() string { return "counter" }
+
+func (counter *Counter) Inc() {
+	counter.value.Add(1)
+}
+
+func (counter *Counter) Add(amount int64) {
+	if amount < 0 {
+		panic(fmt.Sprintf("Counters can't go down, but got %d", amount))
+	}
+	counter.value.Add(amount)
+}
+
+func (counter *Counter) Value() int64 {
+	return counter.value.Load()
+}
+
+// A Gauge holds a value that changes over simulated time, and keeps track of its average weighted by how long
+// it held each value.
+type Gauge struct {
+	mutex       sync.Mutex
+	set         bool
+	value       float64
+	min, max    float64
+	first, last core.Time
+	// The integral of the value over time, from first to last
+	area float64
+}
+
+func (*Gauge) kind() string { return "gauge" }
+
+func timeToFloat(t *core.Time) float64 {
+	value := t.GetTime()
+	result, _ := new(big.Float).SetInt(&value).Float64()
+	return result
+}
+
+// Set changes the value of the gauge at time, which shouldn't be before the last time it was set.
+func (gauge *Gauge) Set(time *core.Time, value float64) {
+	gauge.mutex.Lock()
+	defer gauge.mutex.Unlock()
+	gauge.setLocked(time, value)
+}
+
+// Add changes the value of the gauge by delta at time.
+func (gauge *Gauge) Add(time *core.Time, delta float64) {
+	gauge.mutex.Lock()
+	defer gauge.mutex.Unlock()
+	gauge.setLocked(time, gauge.value+delta)
+}
+
+func (gauge *Gauge) setLocked(time *core.Time, value float64) {
+	if !gauge.set {
+		gauge.set = true
+		gauge.first.Set(time)
+		gauge.min, gauge.max = value, value
+	} else {
+		if time.Cmp(&gauge.last) < 0 {
+			panic(fmt.Sprintf("Gauge was set at %v, before it was last set at %v", time, &gauge.last))
+		}
+		gauge.area += gauge.value * timeToFloat(new(core.Time).Sub(time, &gauge.last))
+		gauge.min = math.Min(gauge.min, value)
+		gauge.max = math.Max(gauge.max, value)
+	}
+	gauge.last.Set(time)
+	gauge.value = value
+}
+
+func (gauge *Gauge) Value() float64 {
+	gauge.mutex.Lock()
+	defer gauge.mutex.Unlock()
+	return gauge.value
+}
+
+// GaugeSnapshot summarizes a gauge.
+type GaugeSnapshot struct {
+	Value, Min, Max float64
+	// The average value between the first and last time the gauge was set
+	TimeWeightedMean float64
+}
+
+func (gauge *Gauge) Snapshot() GaugeSnapshot {
+	gauge.mutex.Lock()
+	defer gauge.mutex.Unlock()
+	snapshot := GaugeSnapshot{Value: gauge.value, Min: gauge.min, Max: gauge.max, TimeWeightedMean: gauge.value}
+	if duration := timeToFloat(new(core.Time).Sub(&gauge.last, &gauge.first)); duration > 0 {
+		snapshot.TimeWeightedMean = gauge.area / duration
+	}
+	return snapshot
+}
+
+// A Sample is a value observed at a simulated time.
+type Sample struct {
+	Time  core.Time
+	Value float64
+}
+
+// A Histogram counts samples into buckets, and keeps each sample along with when it was observed.
+type Histogram struct {
+	mutex   sync.Mutex
+	bounds  []float64
+	counts  []uint64
+	sum     float64
+	samples []Sample
+}
+
+func (*Histogram) kind() string { return "histogram" }
+
+func makeHistogram(bounds []float64) *Histogram {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	// The last bucket catches everything.
+	return &Histogram{bounds: append(bounds, math.Inf(1)), counts: make([]uint64, len(bounds)+1)}
+}
+
+func (histogram *Histogram) Observe(time *core.Time, value float64) {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	bucket := sort.SearchFloat64s(histogram.bounds, value)
+	histogram.counts[bucket]++
+	histogram.sum += value
+	sample := Sample{Value: value}
+	sample.Time.Set(time)
+	histogram.samples = append(histogram.samples, sample)
+}
+
+// HistogramSnapshot summarizes a histogram. Counts[i] is the number of samples at most Bounds[i], and
+// greater than Bounds[i-1]. The last bound is infinite.
+type HistogramSnapshot struct {
+	Bounds  []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+	Samples []Sample
+}
+
+func (histogram *Histogram) Value() HistogramSnapshot {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	return HistogramSnapshot{
+		Bounds:  append([]float64(nil), histogram.bounds...),
+		Counts:  append([]uint64(nil), histogram.counts...),
+		Count:   uint64(len(histogram.samples)),
+		Sum:     histogram.sum,
+		Samples: append([]Sample(nil), histogram.samples...),
+	}
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stanford-ppl/DAM/core"
+)
+
+func TestCounter(t *testing.T) {
+	registry := MakeRegistry()
+	counter := registry.Counter(nil, "sent")
+	counter.Inc()
+	counter.Add(4)
+	if registry.Counter(nil, "sent") != counter {
+		t.Error("Looking up the same counter twice should return the same counter")
+	}
+	if counter.Value() != 5 {
+		t.Errorf("Expected 5, got %d", counter.Value())
+	}
+}
+
+func TestGaugeTimeWeightedMean(t *testing.T) {
+	gauge := MakeRegistry().Gauge(nil, "occupancy")
+	gauge.Set(core.NewTime(0), 0)
+	gauge.Set(core.NewTime(5), 10)
+	gauge.Add(core.NewTime(10), -4)
+	snapshot := gauge.Snapshot()
+	// 0 for 5 ticks, then 10 for 5 ticks.
+	expected := GaugeSnapshot{Value: 6, Min: 0, Max: 10, TimeWeightedMean: 5}
+	if snapshot != expected {
+		t.Errorf("Expected %+v, got %+v", expected, snapshot)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	histogram := MakeRegistry().Histogram(nil, "latency", 10, 1)
+	for i, value := range []float64{0.5, 1, 2, 10, 11, 100} {
+		histogram.Observe(core.NewTime(int64(i)), value)
+	}
+	snapshot := histogram.Value()
+	expectedCounts := []uint64{2, 2, 2}
+	if len(snapshot.Bounds) != 3 || snapshot.Bounds[0] != 1 || snapshot.Bounds[1] != 10 {
+		t.Fatalf("Expected sorted bounds followed by +Inf, got %v", snapshot.Bounds)
+	}
+	for i, count := range expectedCounts {
+		if snapshot.Counts[i] != count {
+			t.Errorf("Expected %v in each bucket, got %v", expectedCounts, snapshot.Counts)
+			break
+		}
+	}
+	if snapshot.Count != 6 || snapshot.Sum != 124.5 {
+		t.Errorf("Expected 6 samples summing to 124.5, got %d summing to %v", snapshot.Count, snapshot.Sum)
+	}
+	if snapshot.Samples[3].Time.Cmp(core.NewTime(3)) != 0 || snapshot.Samples[3].Value != 10 {
+		t.Errorf("Expected the fourth sample to be 10 at time 3, got %v", snapshot.Samples[3])
+	}
+}
+
+func TestTypeMismatch(t *testing.T) {
+	registry := MakeRegistry()
+	registry.Counter(nil, "value")
+	defer (func() {
+		if recover() == nil {
+			t.Error("Looking up a counter as a gauge should panic")
+		}
+	})()
+	registry.Gauge(nil, "value")
+}
+
+// Counts the elements sent by a node over the course of a simulation.
+func simulateWithMetrics(t *testing.T) (*Registry, core.Context) {
+	registry := MakeRegistry()
+	ctx := core.MakePrimitiveContext(nil)
+	var sender *core.SimpleNode[any]
+	sender = core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		sent := registry.Counter(sender, "sent")
+		gap := registry.Histogram(sender, "gap", 1, 2)
+		for i := int64(1); i <= 3; i++ {
+			sent.Inc()
+			gap.Observe(node.TickLowerBound(), float64(i))
+			node.IncrCycles(core.NewTime(i))
+		}
+		registry.Gauge(sender, "active").Set(node.TickLowerBound(), 1)
+	}, (*any)(nil))
+	ctx.AddChild(sender)
+	if _, err := core.Simulate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return registry, sender
+}
+
+func TestWriteCSV(t *testing.T) {
+	registry, sender := simulateWithMetrics(t)
+	var buffer bytes.Buffer
+	if err := registry.Write(&buffer, CSV); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := core.CtxToString(sender)
+	expected := [][]string{
+		{"context", "name", "type", "field", "value"},
+		{name, "sent", "counter", "value", "3"},
+		{name, "gap", "histogram", "count", "3"},
+		{name, "gap", "histogram", "sum", "6"},
+		{name, "gap", "histogram", "le_1", "1"},
+		{name, "gap", "histogram", "le_2", "2"},
+		{name, "gap", "histogram", "le_+Inf", "3"},
+		{name, "active", "gauge", "value", "1"},
+		{name, "active", "gauge", "min", "1"},
+		{name, "active", "gauge", "max", "1"},
+		{name, "active", "gauge", "time_weighted_mean", "1"},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d rows, got %v", len(expected), rows)
+	}
+	for i := range expected {
+		if strings.Join(rows[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Row %d: expected %v, got %v", i, expected[i], rows[i])
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	registry, _ := simulateWithMetrics(t)
+	var buffer bytes.Buffer
+	if err := registry.Write(&buffer, JSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("Expected 3 metrics, got %v", decoded)
+	}
+	if decoded[0]["value"] != float64(3) {
+		t.Errorf("Expected the counter to be 3, got %v", decoded[0]["value"])
+	}
+	samples := decoded[1]["samples"].([]any)
+	if len(samples) != 3 || samples[2].(map[string]any)["time"] != "3" {
+		t.Errorf("Expected 3 samples with the last at time 3, got %v", samples)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	registry, sender := simulateWithMetrics(t)
+	var buffer bytes.Buffer
+	if err := registry.Write(&buffer, Prometheus); err != nil {
+		t.Fatal(err)
+	}
+	label := `{context="` + core.CtxToString(sender) + `"`
+	expected := strings.Join([]string{
+		"# TYPE sent counter",
+		"sent" + label + "} 3",
+		"# TYPE gap histogram",
+		"gap_bucket" + label + `,le="1"} 1`,
+		"gap_bucket" + label + `,le="2"} 2`,
+		"gap_bucket" + label + `,le="+Inf"} 3`,
+		"gap_sum" + label + "} 6",
+		"gap_count" + label + "} 3",
+		"# TYPE active gauge",
+		"active" + label + "} 1",
+		"# TYPE active_time_weighted_mean gauge",
+		"active_time_weighted_mean" + label + "} 1",
+	}, "\n") + "\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buffer.String())
+	}
+}
@@ -23,6 +23,7 @@ type PMU[T datatypes.DAMType] interface {
 	AddWriter(addr *core.CommunicationChannel, data *core.CommunicationChannel,
 		enable utils.Option[*core.CommunicationChannel], ack []*core.CommunicationChannel, tp accesstypes.AccessType,
 	)
+	SetClockDomain(domain *core.ClockDomain)
 }
 
 func MakeBehavior() Behavior {
@@ -14,7 +14,9 @@ import (
 	"github.com/stanford-ppl/DAM/utils"
 )
 
-func (pmu *PMUDataStore[T]) HandleRead(addr datatypes.DAMType, readInfo PMURead, time *core.Time) (result datatypes.DAMType) {
+// HandleRead performs a read on behalf of ctx, which is also where it shows up in a trace.
+func (pmu *PMUDataStore[T]) HandleRead(ctx core.ContextView, addr datatypes.DAMType, readInfo PMURead, time *core.Time) (result datatypes.DAMType) {
+	core.TraceEvent(ctx, "pmu", "read", time, "addr", addr, "type", readInfo.Type)
 	switch accessType := readInfo.Type.(type) {
 	case accesstypes.Gather:
 		// we're in gather read mode
@@ -42,7 +44,9 @@ func (pmu *PMUDataStore[T]) HandleRead(addr datatypes.DAMType, readInfo PMURead,
 	return
 }
 
-func (pmu *PMUDataStore[T]) HandleWrite(addr datatypes.DAMType, enable utils.Option[datatypes.DAMType], data datatypes.DAMType, writeInfo PMUWrite, time *core.Time) {
+// HandleWrite performs a write on behalf of ctx, which is also where it shows up in a trace.
+func (pmu *PMUDataStore[T]) HandleWrite(ctx core.ContextView, addr datatypes.DAMType, enable utils.Option[datatypes.DAMType], data datatypes.DAMType, writeInfo PMUWrite, time *core.Time) {
+	core.TraceEvent(ctx, "pmu", "write", time, "addr", addr, "type", writeInfo.Type)
 	addrScalar, _ := addr.(datatypes.FixedPoint)
 	addrVec, _ := addr.(datatypes.Vector[datatypes.FixedPoint])
 	dataVec, isVec := data.(datatypes.Vector[T])
@@ -97,6 +101,10 @@ type EntryHistory[T any] struct {
 func (eh *EntryHistory[T]) String() string {
 	eh.lock.RLock()
 	defer eh.lock.RUnlock()
+	return eh.stringLocked()
+}
+
+func (eh *EntryHistory[T]) stringLocked() string {
 	if len(eh.history) == 0 {
 		return "History{Empty}"
 	}
@@ -114,7 +122,7 @@ func (eh *EntryHistory[T]) AddEntry(value T, time *core.Time) {
 	if curLen > 0 {
 		// check if the last entry's time is less than current time
 		if eh.history[curLen-1].Time.Cmp(time) >= 0 {
-			panic("The history needs to be monotonically increasing for each entry!")
+			panic(fmt.Sprintf("The history needs to be monotonically increasing for each entry! Time: %v History: %s", time, eh.stringLocked()))
 		}
 	}
 	newEntry := historyEntry[T]{
@@ -136,7 +144,7 @@ func (eh *EntryHistory[T]) ReadEntry(time *core.Time, policy PMUBehavior) T {
 			var x T
 			return x
 		} else {
-			panic(fmt.Sprintf("Trying to read a value before any writes have occurred! Time: %v History: %s", time, eh))
+			panic(fmt.Sprintf("Trying to read a value before any writes have occurred! Time: %v History: %s", time, eh.stringLocked()))
 		}
 	}
 	return eh.history[ind-1].value
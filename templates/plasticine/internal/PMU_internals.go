@@ -2,7 +2,6 @@ package plasticine
 
 import (
 	"fmt"
-	"sync"
 
 	"github.com/stanford-ppl/DAM/core"
 	"github.com/stanford-ppl/DAM/datatypes"
@@ -125,6 +124,16 @@ func (pmu *PMU[T]) AddChild(core.Context) {
 	panic("PMUs have automatically managed children!")
 }
 
+// SetClockDomain runs both of the PMU's pipelines in domain, and counts its latency in cycles of that clock.
+func (pmu *PMU[T]) SetClockDomain(domain *core.ClockDomain) {
+	pmu.reader.SetClockDomain(domain)
+	pmu.writer.SetClockDomain(domain)
+}
+
+func (pmu *PMU[T]) Children() []core.Context {
+	return []core.Context{&pmu.reader, &pmu.writer}
+}
+
 func (pmu *PMU[T]) BlockUntil(time *core.Time) <-chan *core.Time {
 	b1 := pmu.reader.BlockUntil(time)
 	b2 := pmu.writer.BlockUntil(time)
@@ -163,11 +172,7 @@ func (pmu *PMU[T]) Init() {
 }
 
 func (pmu *PMU[T]) Run() {
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go (func() { pmu.reader.Run(); pmu.reader.Cleanup(); wg.Done() })()
-	go (func() { pmu.writer.Run(); pmu.writer.Cleanup(); wg.Done() })()
-	wg.Wait()
+	core.RunChildren(pmu, &pmu.reader, &pmu.writer)
 }
 
 func (pmu *PMU[T]) TickLowerBound() (ret *core.Time) {
@@ -247,13 +252,14 @@ func (pmu *PMUReadPipeline[T]) readTick() bool {
 			// Fetch result now
 			core.GetLogger(pmu).Sugar().Infof("Reading: %+v", pmu.readBacklog)
 			// Wait for the write side to catch up
-			<-pmu.parent.writer.BlockUntil(&pmu.readBacklog.Time)
-			values := pmu.parent.datastore.HandleRead(pmu.readBacklog.AddrValue, pmu.readBacklog.PMURead, &pmu.readBacklog.Time)
+			core.WaitUntil(pmu, &pmu.parent.writer, &pmu.readBacklog.Time)
+			values := pmu.parent.datastore.HandleRead(pmu, pmu.readBacklog.AddrValue, pmu.readBacklog.PMURead, &pmu.readBacklog.Time)
 			for _, v := range channels {
 				v.Enqueue(core.MakeChannelElement(pmu.TickLowerBound(), values))
 			}
 			pmu.readBacklog = nil
 		} else {
+			previous := pmu.SetActivity(core.Stalled)
 			utils.Foreach(channels, func(chn core.OutputChannel) {
 				nextTime := chn.NextTime()
 				if nextTime != nil {
@@ -261,6 +267,7 @@ func (pmu *PMUReadPipeline[T]) readTick() bool {
 				}
 			})
 			pmu.IncrCycles(core.OneTick)
+			pmu.SetActivity(previous)
 			return true
 		}
 	}
@@ -283,17 +290,20 @@ func (pmu *PMUReadPipeline[T]) readTick() bool {
 	// Skip forward to the packet's time
 	addrChan := pmu.InputChannel(readData.Addr)
 	addr, addrStatus := addrChan.Dequeue()
+	previous := pmu.SetActivity(core.Starved)
 	pmu.AdvanceToTime(&addr.Time)
 	if addrStatus == core.Nothing {
 		pmu.IncrCycles(core.OneTick)
+		pmu.SetActivity(previous)
 		return true
 	}
+	pmu.SetActivity(previous)
 	// fmt.Println("Addr:", addr.Time.String(), fmt.Sprintf("%T %#v", addr.Data, addr.Data), "Status:", addrStatus)
 
 	extendedRead := new(PMUReadEntry)
 	extendedRead.PMURead = readData
 	extendedRead.Time.Set(pmu.TickLowerBound())
-	extendedRead.Time.Add(&extendedRead.Time, core.NewTime(pmu.parent.latency))
+	extendedRead.Time.Add(&extendedRead.Time, pmu.CyclesToTime(core.NewTime(pmu.parent.latency)))
 	extendedRead.AddrValue = addr.Data
 	pmu.readBacklog = extendedRead
 	pmu.IncrCycles(core.OneTick)
@@ -368,6 +378,7 @@ func (pmuWriter *PMUWritePipeline[T]) writeTick() bool {
 			pmuWriter.writeBacklog = nil
 		} else {
 			// we can't write yet, so we need to wait a bit
+			previous := pmuWriter.SetActivity(core.Stalled)
 			utils.Foreach(channels, func(chn core.OutputChannel) {
 				nextTime := chn.NextTime()
 				if nextTime != nil {
@@ -375,6 +386,7 @@ func (pmuWriter *PMUWritePipeline[T]) writeTick() bool {
 				}
 			})
 			pmuWriter.IncrCycles(core.OneTick)
+			pmuWriter.SetActivity(previous)
 			return true
 		}
 	}
@@ -393,8 +405,10 @@ func (pmuWriter *PMUWritePipeline[T]) writeTick() bool {
 	// }
 	firstPacket := utils.MinElem(livePackets, PktLT[PMUWrite])
 	if firstPacket.Status == core.Nothing {
+		previous := pmuWriter.SetActivity(core.Starved)
 		pmuWriter.AdvanceToTime(&firstPacket.Time)
 		pmuWriter.IncrCycles(core.OneTick)
+		pmuWriter.SetActivity(previous)
 		return true
 	}
 	writeData := firstPacket.Data
@@ -416,8 +430,8 @@ func (pmuWriter *PMUWritePipeline[T]) writeTick() bool {
 	}
 
 	writeTime := pmuWriter.TickLowerBound()
-	writeTime.Add(writeTime, core.NewTime(pmuWriter.parent.latency-1))
-	pmuWriter.parent.datastore.HandleWrite(addr.Data, enable, data.Data, writeData, writeTime)
+	writeTime.Add(writeTime, pmuWriter.CyclesToTime(core.NewTime(pmuWriter.parent.latency-1)))
+	pmuWriter.parent.datastore.HandleWrite(pmuWriter, addr.Data, enable, data.Data, writeData, writeTime)
 	pmuWriter.writeBacklog = &writeData
 	pmuWriter.IncrCycles(core.OneTick)
 	return true
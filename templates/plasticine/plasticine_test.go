@@ -1,6 +1,8 @@
 package plasticine
 
 import (
+	"bytes"
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -11,6 +13,16 @@ import (
 )
 
 func TestPMURW(t *testing.T) {
+	runPMURW(t)
+}
+
+func BenchmarkPMURW(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		runPMURW(b)
+	}
+}
+
+func runPMURW(t testing.TB) {
 	ctx := core.MakePrimitiveContext(nil)
 	comms := []*core.CommunicationChannel{}
 
@@ -149,3 +161,86 @@ func TestPMURW(t *testing.T) {
 	ctx.Init()
 	ctx.Run()
 }
+
+func TestPMUTopology(t *testing.T) {
+	ctx := core.MakePrimitiveContext(nil)
+	pmu := MakePMU[datatypes.FixedPoint](16, 2, MakeBehavior())
+	ctx.AddChild(pmu)
+
+	writer := core.MakeSimpleNode(func(node *core.SimpleNode[any]) {}, (*any)(nil))
+	wAddr := core.MakeCommunicationChannel[datatypes.FixedPoint](4)
+	wData := core.MakeCommunicationChannel[datatypes.FixedPoint](4)
+	writer.AddOutputChannel(wAddr)
+	writer.AddOutputChannel(wData)
+	ctx.AddChild(writer)
+	pmu.AddWriter(wAddr, wData, utils.None[*core.CommunicationChannel](), nil, accesstypes.Scalar{})
+
+	topology := core.DescribeTopology(ctx)
+	pmuNode := topology.Root.Children[0]
+	if len(pmuNode.Children) != 2 {
+		t.Fatalf("Expected the PMU to have a reader and a writer, got %+v", pmuNode)
+	}
+	writePipeline := pmuNode.Children[1]
+	for _, channel := range topology.Channels {
+		if channel.From != topology.Root.Children[1].ID || channel.To != writePipeline.ID {
+			t.Errorf("Expected the channel to go from the writer to %s, got %+v", writePipeline.Name, channel)
+		}
+	}
+}
+
+func TestPMUTrace(t *testing.T) {
+	ctx := core.MakePrimitiveContext(nil)
+	pmu := MakePMU[datatypes.FixedPoint](16, 2, MakeBehavior())
+	ctx.AddChild(pmu)
+	fpt := datatypes.FixedPointType{Signed: false, Integer: 8, Fraction: 0}
+
+	wAddr := core.MakeCommunicationChannel[datatypes.FixedPoint](1)
+	wData := core.MakeCommunicationChannel[datatypes.FixedPoint](1)
+	wAck := core.MakeCommunicationChannel[datatypes.Bit](1)
+	rAddr := core.MakeCommunicationChannel[datatypes.FixedPoint](1)
+	rData := core.MakeCommunicationChannel[datatypes.FixedPoint](1)
+	node := core.MakeSimpleNode(func(node *core.SimpleNode[any]) {
+		value := datatypes.FixedPoint{Tp: fpt}
+		value.SetInt64(7)
+		node.OutputChannel(0).Enqueue(core.MakeChannelElement(node.TickLowerBound(), datatypes.FixedPoint{Tp: fpt}))
+		node.OutputChannel(1).Enqueue(core.MakeChannelElement(node.TickLowerBound(), value))
+		core.DequeueInputChansByID(node, 0)
+		node.OutputChannel(2).Enqueue(core.MakeChannelElement(node.TickLowerBound(), datatypes.FixedPoint{Tp: fpt}))
+		core.DequeueInputChansByID(node, 1)
+	}, (*any)(nil))
+	node.AddOutputChannel(wAddr)
+	node.AddOutputChannel(wData)
+	node.AddOutputChannel(rAddr)
+	node.AddInputChannel(wAck)
+	node.AddInputChannel(rData)
+	ctx.AddChild(node)
+	pmu.AddWriter(wAddr, wData, utils.None[*core.CommunicationChannel](), []*core.CommunicationChannel{wAck}, accesstypes.Scalar{})
+	pmu.AddReader(rAddr, []*core.CommunicationChannel{rData}, accesstypes.Scalar{})
+
+	tracer := core.MakeChromeTracer()
+	if _, err := core.Simulate(ctx, core.WithTracer(tracer)); err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := tracer.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name     string `json:"name"`
+			Category string `json:"cat"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+	accesses := map[string]int{}
+	for _, event := range trace.TraceEvents {
+		if event.Category == "pmu" {
+			accesses[event.Name]++
+		}
+	}
+	if accesses["read"] != 1 || accesses["write"] != 1 {
+		t.Errorf("Expected one read and one write in the trace, got %v", accesses)
+	}
+}